@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"requestechoer/reqtest"
+)
+
+// readBaselineFile reads results previously written with -output json from the file at path
+func readBaselineFile(path string) (reqtest.Results, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline file: %w", err)
+	}
+
+	defer f.Close()
+	var results reqtest.Results
+	if err := json.NewDecoder(f).Decode(&results); err != nil {
+		return nil, fmt.Errorf("could not parse baseline file, expected results written with -output json: %w", err)
+	}
+
+	return results, nil
+}
+
+// baselineKey identifies the results compared against a baseline, the target is empty unless sent by SendTargets
+type baselineKey struct {
+	target string
+	size   int
+}
+
+// medianLatencies returns the median latency of the successful results of each target and size
+func medianLatencies(results reqtest.Results) map[baselineKey]time.Duration {
+	latencies := make(map[baselineKey][]time.Duration)
+	for _, result := range results {
+		if result.Error == "" {
+			key := baselineKey{target: result.Target, size: result.Size}
+			latencies[key] = append(latencies[key], result.Duration)
+		}
+	}
+
+	medians := make(map[baselineKey]time.Duration, len(latencies))
+	for key, durations := range latencies {
+		slices.Sort(durations)
+		medians[key] = durations[len(durations)/2]
+	}
+
+	return medians
+}
+
+// writeBaselineDiff compares the median latency of each target and size in results against baseline, marking those that got
+// more than threshold percent slower with + and those missing from either run with -, and returns the number that regressed.
+// The target column is only written when either run was sent to several targets
+func writeBaselineDiff(w io.Writer, baseline, results reqtest.Results, threshold float64) int {
+	before, after := medianLatencies(baseline), medianLatencies(results)
+	keys := make([]baselineKey, 0, len(before))
+	for key := range before {
+		keys = append(keys, key)
+	}
+
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b baselineKey) int {
+		return cmp.Or(cmp.Compare(a.target, b.target), cmp.Compare(a.size, b.size))
+	})
+
+	width := 0
+	for _, key := range keys {
+		width = max(width, len(key.target))
+	}
+
+	if width > 0 {
+		width = max(width, len("target"))
+	}
+
+	// target pads the target of key to the widest target, leaving a column only when there are targets
+	target := func(key baselineKey) string {
+		if width == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("%-*s ", width, key.target)
+	}
+
+	regressed := 0
+	fmt.Fprintf(w, "median latency against baseline, regressions are over %v%% slower:\n", threshold)
+	fmt.Fprintf(w, "  %v%12s %14s %14s %10s\n", target(baselineKey{target: "target"}), "size", "baseline", "this run", "change")
+	for _, key := range keys {
+		was, inBaseline := before[key]
+		now, inRun := after[key]
+		switch {
+		case !inBaseline:
+			fmt.Fprintf(w, "- %v%12v %14s %14s\n", target(key), key.size, "missing", now.Round(time.Microsecond))
+		case !inRun:
+			fmt.Fprintf(w, "- %v%12v %14s %14s\n", target(key), key.size, was.Round(time.Microsecond), "missing")
+		default:
+			change := (float64(now)/float64(max(was, 1)) - 1) * 100
+			marker := " "
+			if change > threshold {
+				marker = "+"
+				regressed++
+			}
+
+			fmt.Fprintf(w, "%v %v%12v %14s %14s %+9.1f%%\n", marker, target(key), key.size, was.Round(time.Microsecond), now.Round(time.Microsecond), change)
+		}
+	}
+
+	return regressed
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"requestechoer/reqtest"
+)
+
+func TestWriteBaselineDiffByTarget(t *testing.T) {
+	// both targets send the same sizes, only b got slower, which a median across targets would hide
+	baseline := reqtest.Results{
+		{Target: "a", Size: 2, Duration: 10 * time.Millisecond},
+		{Target: "b", Size: 2, Duration: 10 * time.Millisecond},
+	}
+
+	results := reqtest.Results{
+		{Target: "a", Size: 2, Duration: 10 * time.Millisecond},
+		{Target: "b", Size: 2, Duration: 20 * time.Millisecond},
+		{Target: "c", Size: 2, Duration: 10 * time.Millisecond},
+	}
+
+	var out strings.Builder
+	if regressed := writeBaselineDiff(&out, baseline, results, 10); regressed != 1 {
+		t.Errorf("got %v regressions, want 1", regressed)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"  a                 2           10ms           10ms      +0.0%",
+		"+ b                 2           10ms           20ms    +100.0%",
+		"- c                 2        missing           10ms",
+	}
+
+	if got := lines[2:]; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diff:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWriteBaselineDiffWithoutTargets(t *testing.T) {
+	baseline := reqtest.Results{{Size: 2, Duration: 10 * time.Millisecond}}
+	results := reqtest.Results{{Size: 2, Duration: 10 * time.Millisecond}}
+	var out strings.Builder
+	writeBaselineDiff(&out, baseline, results, 10)
+	if strings.Contains(out.String(), "target") {
+		t.Errorf("got a target column without targets:\n%v", out.String())
+	}
+}
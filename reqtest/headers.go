@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"strings"
+
+	"requestechoer/reqtest"
+)
+
+// headerVar defines a repeatable header flag with the given name and usage
+func headerVar(name, usage string) *headerFlag {
+	h := &headerFlag{}
+	flag.Var(h, name, usage)
+	return h
+}
+
+// headerFlag collects repeated "Key: Value" flags into a header
+type headerFlag struct {
+	header http.Header
+}
+
+func (h *headerFlag) String() string {
+	if h == nil || h.header == nil {
+		return ""
+	}
+
+	parts := make([]string, 0, len(h.header))
+	for key, values := range h.header {
+		for _, value := range values {
+			parts = append(parts, key+": "+value)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (h *headerFlag) Set(value string) error {
+	key, val, err := reqtest.ParseHeader(value)
+	if err != nil {
+		return err
+	}
+
+	if h.header == nil {
+		h.header = make(http.Header)
+	}
+
+	h.header.Add(key, val)
+	return nil
+}
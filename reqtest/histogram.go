@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"requestechoer/reqtest"
+)
+
+// histogramWidth is the length of the bar for the fullest bucket
+const histogramWidth = 40
+
+// writeHistogram renders the latencies of the successful results as an ASCII histogram of evenly sized buckets
+func writeHistogram(w io.Writer, results reqtest.Results, buckets int) {
+	var latencies []time.Duration
+	for _, result := range results {
+		if result.Error == "" {
+			latencies = append(latencies, result.Duration)
+		}
+	}
+
+	if len(latencies) == 0 || buckets < 1 {
+		return
+	}
+
+	lowest, highest := latencies[0], latencies[0]
+	for _, latency := range latencies {
+		lowest = min(lowest, latency)
+		highest = max(highest, latency)
+	}
+
+	width := max((highest-lowest)/time.Duration(buckets), 1)
+	counts := make([]int, buckets)
+	for _, latency := range latencies {
+		counts[min(int((latency-lowest)/width), buckets-1)]++
+	}
+
+	fullest := 0
+	for _, count := range counts {
+		fullest = max(fullest, count)
+	}
+
+	fmt.Fprintf(w, "latency histogram of %v requests:\n", len(latencies))
+	for i, count := range counts {
+		start := lowest + time.Duration(i)*width
+		end := start + width
+		if i == buckets-1 {
+			end = highest
+		}
+
+		bar := strings.Repeat("#", count*histogramWidth/fullest)
+		fmt.Fprintf(w, "%12s - %-12s | %-*s %v\n", start.Round(time.Microsecond), end.Round(time.Microsecond), histogramWidth, bar, count)
+	}
+}
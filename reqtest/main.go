@@ -1,46 +1,181 @@
 package main
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/hex"
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+
+	"requestechoer/reqtest"
 )
 
 var (
-	respDelay     = flag.Duration("resp-delay", 0*time.Second, "Adds a delay before responding to a request in listen mode")
-	sendStartStep = flag.Int("start-step", 1, "The number of bytes to start sending at in powers of 2 (e.g, a value of 1 will start at 2 bytes, a value of 15 will start at 2^15 bytes)")
-	sendEndStep   = flag.Int("end-step", 25, "The number of bytes to end sending at in powers of 2 (e.g, a value of 25 will stop sending requests once payload sizes hit 2^25 bytes)")
+	listenNetwork   = flag.String("network", "tcp", "The network to listen on in listen mode, one of tcp, tcp4, or tcp6 to only bind IPv4 or IPv6")
+	rawTCP          = flag.Bool("raw-tcp", false, "Accepts plain TCP connections and counts the bytes read in listen mode, and writes payloads over plain TCP connections to a host:port in send mode, bypassing HTTP")
+	routesFile      = flag.String("routes", "", "Path to a JSON file mapping path patterns to canned responses in listen mode. Unmatched requests are handled as usual")
+	respDelay       = flag.Duration("resp-delay", 0*time.Second, "Adds a delay before responding to a request in listen mode")
+	allowGet        = flag.Bool("allow-get", false, "Handles GET requests like other requests in listen mode, rather than responding with 405 and a page describing the listener")
+	reflectHeaders  = flag.String("reflect-headers", "", "A comma separated list of request headers, or * for all of them, to copy into each response under reflect-prefix in listen mode, showing which headers were stripped or rewritten on the way")
+	reflectPrefix   = flag.String("reflect-prefix", "X-Echo-", "The prefix added to the names of request headers copied into responses with reflect-headers in listen mode")
+	respTrailers    = headerVar("trailer", "A trailer to send after every response body in listen mode in the form \"Key: Value\", declared in the Trailer header with HTTP/1.1 responses chunked so it can be sent. May be repeated")
+	respHeaders     = headerVar("resp-header", "A header to set on every response in listen mode in the form \"Key: Value\", replacing defaults such as Content-Type. May be repeated")
+	websocketEcho   = flag.Bool("websocket", false, "Accepts WebSocket connections at /ws in listen mode and echoes each message back")
+	echoBody        = flag.Bool("echo", false, "Writes the received request body back in the response in listen mode")
+	reflectReq      = flag.Bool("reflect", false, "Responds with a JSON description of the received request's method, path, query, headers, and body length in listen mode. Conflicts with echo")
+	responsesFile   = flag.String("responses", "", "Path to a JSON array of responses, each with a status, headers, body, and delay, served in order one per request in listen mode regardless of its content, looping once all have been served. Requests matching routes are handled by them instead")
+	responsesStrict = flag.Bool("responses-strict", false, "Serves the responses file only once in listen mode, handling requests as usual with status, echo, and the other listen flags after the last one")
+	respStatus      = flag.String("status", "200", "The status code to respond with in listen mode. A comma separated list such as 200,200,503 is cycled through per request")
+	maxBodyBytes    = flag.Int64("max-body-bytes", 0, "The maximum request body size accepted in listen mode before responding with 413, 0 for no limit. Gzip bodies are limited both compressed and decompressed")
+	respSize        = flag.String("resp-size", "", "Streams this many bytes of generated data back in each response body in listen mode, with an optional suffix such as 512KB or 1MiB. Conflicts with echo and reflect")
+	readBuffer      = flag.String("read-buffer", "", "The size of the buffer request bodies are read through in listen mode, with an optional suffix such as 512KB or 1MiB. Defaults to 32KiB")
+	readRate        = flag.String("read-rate", "", "Limits how fast request bodies, or connections with raw-tcp, are read in listen mode, in bytes per second with an optional suffix such as 512KB or 1MiB")
+	maxRequests     = flag.Int64("max-requests", 0, "The number of requests, or connections with raw-tcp, to serve in listen mode before shutting down, 0 for no limit")
+	saveDir         = flag.String("save-dir", "", "Directory to save each received request body to in listen mode")
+	verbose         = flag.Bool("verbose", false, "Logs the request line and headers of each request in listen mode, and the protocol and connection reuse of each request in send mode")
+	maxDumpBytes    = flag.Int("max-dump-bytes", 1024, "The maximum number of body bytes to log with verbose in listen mode, 0 to not log the body")
+	tlsCert         = flag.String("tls-cert", "", "Path to a TLS certificate to serve HTTPS with in listen mode. Requires tls-key")
+	tlsKey          = flag.String("tls-key", "", "Path to the TLS private key for tls-cert in listen mode. Requires tls-cert")
+	clientCA        = flag.String("client-ca", "", "Path to PEM encoded CA certificates that clients must present a certificate signed by when serving TLS in listen mode")
+	tlsMinVersion   = flag.String("tls-min-version", "", "The minimum TLS version to accept when serving TLS in listen mode, one of 1.0, 1.1, 1.2, or 1.3. Defaults to crypto/tls's minimum")
+	tlsMaxVersion   = flag.String("tls-max-version", "", "The maximum TLS version to accept when serving TLS in listen mode, one of 1.0, 1.1, 1.2, or 1.3. Defaults to 1.3")
+	tlsCiphers      = flag.String("tls-ciphers", "", "A comma separated list of cipher suite names, such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, to restrict TLS 1.2 and earlier to when serving TLS in listen mode")
+	tlsSelfSigned   = flag.Bool("tls-self-signed", false, "Serves TLS with a generated self-signed certificate for localhost in listen mode")
+	failRate        = flag.Float64("fail-rate", 0, "The fraction of requests, from 0 to 1, to randomly respond to with a 5xx status in listen mode")
+	dropRate        = flag.Float64("drop-rate", 0, "The fraction of requests, from 0 to 1, to randomly drop the connection of partway through the response in listen mode")
+	hashBody        = flag.Bool("hash", false, "Returns the SHA-256 of each received body in the X-Body-SHA256 response header in listen mode, sent as a trailer with echo")
+	requestLogFile  = flag.String("log-file", "", "Appends a JSON line per request with its time, method, path, remote address, body size, status, and duration to this file in listen mode")
+	harFile         = flag.String("har", "", "Records received requests to a HAR 1.2 file written on shutdown in listen mode")
+	harBodyBytes    = flag.Int("har-body-bytes", 1024, "The number of body bytes to record per request with har in listen mode. Each body's size and SHA-256 are always recorded")
+	pprofAddress    = flag.String("pprof", "", "An address to serve net/http/pprof handlers on in listen mode, separate from the address requests are served on")
+	metricsAddress  = flag.String("metrics", "", "An address to serve Prometheus metrics on at /metrics in listen mode, separate from the address requests are served on")
+	readTimeout     = flag.Duration("read-timeout", 0, "How long reading each request's headers and body may take in listen mode, 0 for no limit")
+	writeTimeout    = flag.Duration("write-timeout", 0, "How long handling each request may take from the end of its headers to the end of the response in listen mode, 0 for no limit")
+	idleTimeout     = flag.Duration("idle-timeout", 0, "How long a keep-alive connection may wait for its next request in listen mode. Defaults to read-timeout")
+	shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "How long to wait for in-flight requests to complete when shutting down in listen mode")
+	sendStartStep   = flag.Int("start-step", 1, "The number of bytes to start sending at in powers of 2 (e.g, a value of 1 will start at 2 bytes, a value of 15 will start at 2^15 bytes)")
+	sendEndStep     = flag.Int("end-step", 25, "The number of bytes to end sending at in powers of 2 (e.g, a value of 25 will stop sending requests once payload sizes hit 2^25 bytes)")
+	sendStepMode    = flag.String("step-mode", reqtest.StepModePow2, "How payload sizes increase between requests in send mode, either pow2 or linear")
+	sendStepSize    = flag.Int("step-size", 1<<20, "The number of bytes to add to each payload in linear step-mode")
+	sendIterations  = flag.Int("iterations", 1, "The number of times to run the whole ramp from start-step to end-step in send mode, aggregating stats across every iteration")
+	sendRepeat      = flag.Int("repeat", 1, "The number of times to send each payload size in send mode")
+	sendOutput      = flag.String("output", outputText, "The format of results in send mode, one of text, json or csv")
+	histBuckets     = flag.Int("histogram-buckets", 10, "The number of buckets in the latency histogram printed after a send with repeat or duration, 0 to not print it")
+	sendConcurrency = flag.Int("concurrency", 1, "The number of concurrent workers sending requests in send mode")
+	parallelTargets = flag.Bool("parallel-targets", false, "Sends to every address given to send at the same time rather than one after another")
+	continueOnError = flag.Bool("continue-on-error", false, "Keeps sending the remaining sizes after a request fails in send mode, reporting every failure at the end")
+	sendTimeout     = flag.Duration("timeout", 0, "How long each request may take in send mode, 0 for no limit")
+	sendTrace       = flag.Bool("trace", false, "Logs how long DNS lookup, connecting, the TLS handshake, and the first response byte took for each request in send mode")
+	sendPropagate   = flag.Bool("trace-propagation", false, "Injects W3C traceparent and tracestate headers for a new trace into each request in send mode, logging the trace IDs")
+	sendNoKeepAlive = flag.Bool("disable-keepalive", false, "Disables keep-alives in send mode so every request is sent over a new connection")
+	sendMaxConns    = flag.Int("max-conns-per-host", 0, "Limits how many connections may be open to the host at once in send mode, 0 for no limit")
+	sendReadResp    = flag.Bool("read-response", false, "Reads and discards all of each response body in send mode so connections can be reused. Otherwise only the first 64KiB is drained, and larger bodies stop connections from being pooled")
+	sendExpect      = flag.Bool("expect-continue", false, "Sends requests with Expect: 100-continue in send mode, so the body is only sent once the server responds with 100 Continue")
+	sendRespBytes   = flag.Int64("response-bytes", 0, "Stops reading each response after this many bytes with raw-tcp in send mode, 0 to read until the connection is closed")
+	clientCert      = flag.String("client-cert", "", "Path to a TLS certificate to present to servers requesting client authentication in send mode. Requires client-key")
+	clientKey       = flag.String("client-key", "", "Path to the TLS private key for client-cert in send mode. Requires client-cert")
+	caCert          = flag.String("ca-cert", "", "Path to PEM encoded CA certificates to trust instead of the system roots in send mode")
+	insecure        = flag.Bool("insecure", false, "Skips verifying the server's TLS certificate in send mode, for self-signed test servers")
+	sendProxy       = flag.String("proxy", "", "An http, https, or socks5 proxy URL to send requests through in send mode. Defaults to the proxy from the environment")
+	sendHTTP3       = flag.Bool("http3", false, "Sends requests over HTTP/3 only in send mode, using QUIC over UDP. Requires an https address, as QUIC is always encrypted with TLS 1.3")
+	sendHTTP2       = flag.Bool("http2", false, "Sends requests over HTTP/2 only in send mode, using h2c with prior knowledge for http and unix socket addresses")
+	sendNoRedirect  = flag.Bool("no-redirect", false, "Reports redirect responses as failures in send mode instead of following them")
+	sendForce       = flag.Bool("force", false, "Sends generated payloads larger than 256MiB in send mode, such as with an end-step above 28, which are otherwise refused. Payload files are never refused")
+	sendDryRun      = flag.Bool("dry-run", false, "Prints the payload sizes, target, method, and headers that would be used in send mode without sending any requests")
+	sendWarmup      = flag.Int("warmup", 0, "The number of throwaway requests of the start-step size to send in send mode before the measured run, excluded from results and stats")
+	sendDuration    = flag.Duration("duration", 0, "Repeatedly sends the start-step payload size for this long in send mode instead of stepping through sizes")
+	sendPayloadFile = flag.String("payload-file", "", "Path to a file to send as the request body in send mode, or - for stdin. Ignores the step flags")
+	sendTemplate    = flag.String("template", "", "Path to a text/template file, or - for stdin, rendered as the body of each request in send mode with {{.Index}}, {{.UUID}}, and {{.Timestamp}} substituted. Ignores the step flags")
+	sendReplay      = flag.String("replay", "", "Path to a HAR file recorded with har, or JSON lines of requests, to replay in order in send mode instead of sending generated payloads. Recorded paths and queries are joined onto path and query")
+	sendContentType = flag.String("content-type", "", "The Content-Type header of requests in send mode. Defaults to application/octet-stream with payload-file")
+	sendChunked     = flag.Bool("chunked", false, "Sends bodies with chunked transfer encoding rather than a Content-Length in send mode")
+	sendMultipart   = flag.Bool("multipart", false, "Wraps each payload as a file in a multipart/form-data body in send mode")
+	sendFieldName   = flag.String("field-name", "file", "The form field name of the payload with multipart in send mode")
+	sendPattern     = flag.String("pattern", reqtest.PatternRandom, "The payload pattern to generate in send mode, one of random, zeros or repeating")
+	sizesFile       = flag.String("sizes-file", "", "Path to a file of payload sizes to send in order in send mode, one per line with an optional suffix such as 512KB or 1MiB. Ignores the step flags, and payloads are streamed as with stream")
+	baselineFile    = flag.String("baseline", "", "Path to results previously written with -output json to compare the median latency of each size against after sending in send mode, failing if any size regressed")
+	regressionLimit = flag.Float64("regression-threshold", 10, "The percent a size's median latency may increase over baseline in send mode before it counts as a regression")
+	sendStream      = flag.Bool("stream", false, "Generates each payload as it is sent in send mode rather than allocating it up front, keeping memory flat with payloads of any size. Conflicts with payload-file, template, replay, multipart, gzip, and verify")
+	sendRaw         = flag.Bool("raw", false, "Sends raw random bytes rather than hex encoded bytes in send mode")
+	sendGzip        = flag.Bool("gzip", false, "Compresses request bodies with gzip in send mode")
+	sendAcceptGzip  = flag.Bool("accept-gzip", false, "Sends Accept-Encoding: gzip in send mode and decompresses gzip responses before reading or verifying them, logging the compressed and decompressed sizes")
+	sendVerify      = flag.Bool("verify", false, "Verifies the response body matches the payload sent in send mode, for use with a listener running with echo")
+	sendSeed        = flag.Int64("seed", 0, "Seeds payload generation in send mode so the same payloads are produced across runs. For reproducibility only, seeded payloads are not cryptographically random")
+	sendRetries     = flag.Int("retries", 0, "How many times to retry a request that fails with a retryable error, such as a 5xx response or dropped connection, in send mode")
+	sendBackoff     = flag.Duration("retry-backoff", 100*time.Millisecond, "How long to wait before the first retry in send mode, doubling for each retry after")
+	sendInterval    = flag.Duration("interval", 0, "How long to wait between requests in send mode, such as 100ms. 0 sends requests back to back")
+	sendJitter      = flag.Float64("jitter", 0, "Randomizes each interval by up to this percent either way in send mode")
+	sendProgress    = flag.Duration("progress-interval", 0, "How often to log running totals of completed requests, failures, and throughput in send mode, 0 to not log progress")
+	sendMaxTotal    = flag.String("max-total-bytes", "", "Stops sending in send mode before the body bytes sent across every request, including retries, would exceed this, with an optional suffix such as 512KB or 1MiB")
+	sendBandwidth   = flag.String("bandwidth", "", "Limits how fast request bodies are written in send mode, in bytes per second with an optional suffix such as 512KB or 1MiB")
+	userAgent       = flag.String("user-agent", reqtest.DefaultUserAgent(), "The User-Agent header of requests in send mode, including retries and warm-up requests")
+	sendCookies     = flag.Bool("cookies", false, "Keeps cookies set by responses in send mode and sends them with later requests, logging the names of cookies received and sent")
+	sendHeaders     = headerVar("header", "A header to add to requests in send mode in the form \"Key: Value\", a Host header setting the host sent. May be repeated")
+	sendPath        = flag.String("path", "", "A path to join onto the address in send mode, such as /upload")
+	expectStatus    = statusVar("expect-status", "A response status code, or comma separated list such as 200,201,204, accepted as success in send mode. May be repeated. Defaults to 200")
+	sendQuery       = queryVar("query", "A query parameter to append to the address in send mode in the form \"key=value\", with the value URL encoded. May be repeated")
+	sendBearer      = flag.String("bearer", "", "A bearer token to send in the Authorization header of requests in send mode. Conflicts with basic")
+	sendBasic       = flag.String("basic", "", "A user:pass pair to send as basic auth with requests in send mode. Conflicts with bearer")
+	sendMethod      = flag.String("method", http.MethodPut, "The HTTP method to use for requests in send mode")
+	logFormat       = flag.String("log-format", reqtest.LogFormatText, "The format of logs, either text or json")
+	logLevel        = flag.String("log-level", "info", "The minimum level of logs to print, one of debug, info, warn or error. Summaries are always printed")
+	showVersion     = flag.Bool("version", false, "Prints the version, commit, build date and Go version, like the version command")
+	quiet           = flag.Bool("quiet", false, "Only prints warnings, errors and summaries. Shorthand for -log-level warn")
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputCSV  = "csv"
 )
 
 func main() {
 	flag.Parse()
+	if *showVersion {
+		printVersion()
+		os.Exit(0)
+	}
+
 	args := flag.Args()
 	if len(args) == 0 {
 		printUsage()
 		os.Exit(1)
 	}
 
+	logger, err := newLogger()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	switch args[0] {
 	case "listen":
-		if err := listen(args[1:]); err != nil {
-			log.Printf("failed to listen: %v\n", err)
+		if err := listen(ctx, logger, args[1:]); err != nil {
+			logger.Error(fmt.Sprintf("failed to listen: %v", err), "error", err)
 			os.Exit(1)
 		}
 	case "send":
-		if err := send(args[1:]); err != nil {
-			log.Printf("failed to send: %v\n", err)
+		if err := send(ctx, logger, args[1:]); err != nil {
+			logger.Error(fmt.Sprintf("failed to send: %v", err), "error", err)
 			os.Exit(1)
 		}
+	case "version":
+		printVersion()
 	default:
-		log.Printf("unknown arg %v", args[0])
+		logger.Error(fmt.Sprintf("unknown arg %v", args[0]))
 		printUsage()
 		os.Exit(1)
 	}
@@ -48,6 +183,30 @@ func main() {
 	os.Exit(0)
 }
 
+// printVersion prints the build info of the binary
+func printVersion() {
+	info := reqtest.ReadBuildInfo()
+	fmt.Printf("reqtest %v\ncommit: %v\nbuilt: %v\ngo: %v\n", info.Version, info.Commit, info.BuildDate, info.GoVersion)
+}
+
+// newLogger creates the logger for the log flags
+func newLogger() (*slog.Logger, error) {
+	if *quiet && isFlagSet("log-level") {
+		return nil, errors.New("quiet cannot be used with log-level")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid log-level %v, must be one of: debug, info, warn, error", *logLevel)
+	}
+
+	if *quiet {
+		level = slog.LevelWarn
+	}
+
+	return reqtest.NewLogger(os.Stderr, *logFormat, level)
+}
+
 func printUsage() {
 	fmt.Println(`This is a tool that will listen for any requests and echo them to stdout.
 It can also send requests of increasing sizes to a listener.
@@ -55,98 +214,469 @@ It can also send requests of increasing sizes to a listener.
 To listen:
 [binary] listen <address>
 
+While listening, send SIGUSR1 to log the total requests, bytes, requests in flight and uptime.
+
 To send:
-[binary] send <address>`)
+[binary] send <address> [address...]
+
+Given more than one address, send runs against each in turn, or all at once with parallel-targets, and compares them at the end.
+
+To print the version:
+[binary] version
+
+Either address may be a unix domain socket such as unix:/tmp/reqtest.sock`)
 }
 
-func listen(args []string) error {
+func listen(ctx context.Context, logger *slog.Logger, args []string) error {
 	if len(args) != 1 {
 		printUsage()
 		return errors.New("listen expects exactly 1 argument")
 	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// ignore gets
-		if r.Method == "GET" {
-			return
+	statuses, err := reqtest.ParseStatuses(*respStatus)
+	if err != nil {
+		return err
+	}
+
+	var readRateBytes int64
+	if *readRate != "" {
+		readRateBytes, err = reqtest.ParseByteSize(*readRate)
+		if err != nil {
+			return fmt.Errorf("invalid read-rate: %w", err)
+		}
+	}
+
+	var readBufferBytes int64
+	if *readBuffer != "" {
+		readBufferBytes, err = reqtest.ParseByteSize(*readBuffer)
+		if err != nil {
+			return fmt.Errorf("invalid read-buffer: %w", err)
 		}
+	}
 
-		log.Println("received request")
-		if respDelay != nil && *respDelay > 0*time.Second {
-			log.Printf("waiting %s before reading/responding...", *respDelay)
-			time.Sleep(*respDelay)
+	var respSizeBytes int64
+	if *respSize != "" {
+		respSizeBytes, err = reqtest.ParseByteSize(*respSize)
+		if err != nil {
+			return fmt.Errorf("invalid resp-size: %w", err)
 		}
+	}
 
-		bodyBytes, err := io.ReadAll(r.Body)
+	var tlsMin, tlsMax uint16
+	if *tlsMinVersion != "" {
+		tlsMin, err = reqtest.ParseTLSVersion(*tlsMinVersion)
 		if err != nil {
-			log.Printf("error reading body: %v\n", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return fmt.Errorf("invalid tls-min-version: %w", err)
 		}
+	}
 
-		log.Printf("read %v bytes from body\n", len(bodyBytes))
-	})
+	if *tlsMaxVersion != "" {
+		tlsMax, err = reqtest.ParseTLSVersion(*tlsMaxVersion)
+		if err != nil {
+			return fmt.Errorf("invalid tls-max-version: %w", err)
+		}
+	}
+
+	var ciphers []uint16
+	if *tlsCiphers != "" {
+		ciphers, err = reqtest.ParseCipherSuites(*tlsCiphers)
+		if err != nil {
+			return fmt.Errorf("invalid tls-ciphers: %w", err)
+		}
+	}
 
-	log.Printf("listening on %v\n", args[0])
-	return http.ListenAndServe(args[0], nil)
+	var reflected []string
+	if *reflectHeaders != "" {
+		for _, name := range strings.Split(*reflectHeaders, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				reflected = append(reflected, name)
+			}
+		}
+	}
+
+	var routes map[string]reqtest.Route
+	if *routesFile != "" {
+		routes, err = readRoutesFile(*routesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	var responses []reqtest.Route
+	if *responsesFile != "" {
+		responses, err = readResponsesFile(*responsesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	return reqtest.Listen(ctx, reqtest.ListenConfig{
+		Address:         args[0],
+		Network:         *listenNetwork,
+		RawTCP:          *rawTCP,
+		Routes:          routes,
+		Responses:       responses,
+		ResponsesStrict: *responsesStrict,
+		RespDelay:       *respDelay,
+		AllowGet:        *allowGet,
+		Header:          respHeaders.header,
+		Trailer:         respTrailers.header,
+		ReflectHeaders:  reflected,
+		ReflectPrefix:   *reflectPrefix,
+		Echo:            *echoBody,
+		WebSocket:       *websocketEcho,
+		RespSize:        respSizeBytes,
+		Reflect:         *reflectReq,
+		Statuses:        statuses,
+		MaxBodyBytes:    *maxBodyBytes,
+		ReadBuffer:      int(readBufferBytes),
+		ReadRate:        readRateBytes,
+		MaxRequests:     *maxRequests,
+		SaveDir:         *saveDir,
+		Verbose:         *verbose,
+		MaxDumpBytes:    *maxDumpBytes,
+		TLSCert:         *tlsCert,
+		TLSKey:          *tlsKey,
+		TLSSelfSigned:   *tlsSelfSigned,
+		ClientCA:        *clientCA,
+		TLSMinVersion:   tlsMin,
+		TLSMaxVersion:   tlsMax,
+		TLSCiphers:      ciphers,
+		FailRate:        *failRate,
+		DropRate:        *dropRate,
+		Hash:            *hashBody,
+		ReadTimeout:     *readTimeout,
+		WriteTimeout:    *writeTimeout,
+		IdleTimeout:     *idleTimeout,
+		ShutdownTimeout: *shutdownTimeout,
+		HARFile:         *harFile,
+		HARBodyBytes:    *harBodyBytes,
+		LogFile:         *requestLogFile,
+		PprofAddress:    *pprofAddress,
+		MetricsAddress:  *metricsAddress,
+		StatsSignal:     statsSignal(),
+		Logger:          logger,
+	})
 }
 
-func send(args []string) error {
-	if len(args) != 1 {
+func send(ctx context.Context, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
 		printUsage()
-		return errors.New("send expects exactly 1 argument")
+		return errors.New("send expects at least 1 argument")
 	}
 
-	client := &http.Client{
-		Timeout: 0,
+	cfg := reqtest.SendConfig{
+		Address:         args[0],
+		Method:          *sendMethod,
+		StartStep:       *sendStartStep,
+		EndStep:         *sendEndStep,
+		StepMode:        *sendStepMode,
+		StepSize:        *sendStepSize,
+		Repeat:          *sendRepeat,
+		Iterations:      *sendIterations,
+		Concurrency:     *sendConcurrency,
+		ContinueOnError: *continueOnError,
+		Timeout:         *sendTimeout,
+		Verbose:         *verbose,
+		Trace:           *sendTrace,
+		Propagate:       *sendPropagate,
+		NoKeepAlive:     *sendNoKeepAlive,
+		MaxConnsPerHost: *sendMaxConns,
+		ReadResponse:    *sendReadResp,
+		ExpectContinue:  *sendExpect,
+		RawTCP:          *rawTCP,
+		ResponseBytes:   *sendRespBytes,
+		ClientCert:      *clientCert,
+		ClientKey:       *clientKey,
+		CACert:          *caCert,
+		Insecure:        *insecure,
+		Proxy:           *sendProxy,
+		HTTP2:           *sendHTTP2,
+		HTTP3:           *sendHTTP3,
+		NoRedirect:      *sendNoRedirect,
+		Force:           *sendForce,
+		DryRun:          *sendDryRun,
+		Warmup:          *sendWarmup,
+		Duration:        *sendDuration,
+		Path:            *sendPath,
+		Query:           sendQuery.query,
+		ExpectStatus:    expectStatus.codes,
+		UserAgent:       *userAgent,
+		Cookies:         *sendCookies,
+		Header:          sendHeaders.header,
+		BearerToken:     *sendBearer,
+		BasicAuth:       *sendBasic,
+		ContentType:     *sendContentType,
+		Pattern:         *sendPattern,
+		Raw:             *sendRaw,
+		Stream:          *sendStream,
+		Gzip:            *sendGzip,
+		AcceptGzip:      *sendAcceptGzip,
+		Chunked:         *sendChunked,
+		Verify:          *sendVerify,
+		Retries:         *sendRetries,
+		RetryBackoff:    *sendBackoff,
+		Interval:        *sendInterval,
+		Progress:        *sendProgress,
+		Jitter:          *sendJitter,
+		Logger:          logger,
 	}
 
-	var start uint = 1
-	var end uint = 25
-	if sendStartStep != nil && *sendStartStep > 0 {
-		if *sendStartStep >= 32 {
-			return fmt.Errorf("start-step cannot be greater than 31")
+	switch *sendOutput {
+	case outputText:
+	case outputJSON, outputCSV:
+		// the human readable logs are replaced by the results written to stdout, other than the plan of a dry run
+		if !cfg.DryRun {
+			cfg.Logger = slog.New(slog.DiscardHandler)
 		}
-		start = uint(*sendStartStep)
+	default:
+		return fmt.Errorf("invalid output %v, must be one of: %v, %v, %v", *sendOutput, outputText, outputJSON, outputCSV)
+	}
+
+	if *sendMultipart {
+		cfg.MultipartField = *sendFieldName
 	}
 
-	if sendEndStep != nil && *sendEndStep > 0 {
-		if *sendEndStep >= 32 {
-			return fmt.Errorf("end-step cannot be greater than 31")
+	if *sendBandwidth != "" {
+		bandwidth, err := reqtest.ParseByteSize(*sendBandwidth)
+		if err != nil {
+			return fmt.Errorf("invalid bandwidth: %w", err)
+		}
+
+		cfg.Bandwidth = bandwidth
+	}
+
+	if *sendMaxTotal != "" {
+		maxTotal, err := reqtest.ParseByteSize(*sendMaxTotal)
+		if err != nil {
+			return fmt.Errorf("invalid max-total-bytes: %w", err)
 		}
-		end = uint(*sendEndStep)
+
+		cfg.MaxTotalBytes = maxTotal
 	}
 
-	if end < start {
-		return fmt.Errorf("end-step cannot be less than start-step")
+	if isFlagSet("seed") {
+		cfg.Seed = sendSeed
 	}
 
-	maxBytes := 1 << end
-	bytesToSend := 1 << start
-	for bytesToSend <= maxBytes {
-		log.Printf("sending %v bytes\n", bytesToSend)
-		b := make([]byte, bytesToSend/2)
-		if _, err := rand.Read(b); err != nil {
-			return fmt.Errorf("failed to generate bytes: %w", err)
+	if *sendPayloadFile != "" {
+		payload, err := readPayloadFile(*sendPayloadFile)
+		if err != nil {
+			return err
 		}
 
-		bodyStr := hex.EncodeToString(b)
-		req, err := http.NewRequest("PUT", args[0], bytes.NewReader([]byte(bodyStr)))
+		cfg.Payload = payload
+	}
+
+	if *sendTemplate != "" {
+		tmpl, err := readPayloadFile(*sendTemplate)
 		if err != nil {
-			return fmt.Errorf("could not make request: %w", err)
+			return err
 		}
 
-		resp, err := client.Do(req)
+		cfg.Template = string(tmpl)
+	}
+
+	if *sizesFile != "" {
+		sizes, err := readSizesFile(*sizesFile)
 		if err != nil {
-			return fmt.Errorf("could not execute request: %w", err)
+			return err
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("did not get 200 response, got %v", resp.StatusCode)
+		cfg.Sizes = sizes
+	}
+
+	if *sendReplay != "" {
+		replay, err := readReplayFile(*sendReplay)
+		if err != nil {
+			return err
 		}
 
-		bytesToSend <<= 1
+		cfg.Replay = replay
+	}
+
+	var baseline reqtest.Results
+	if *baselineFile != "" {
+		if *regressionLimit < 0 {
+			return errors.New("regression-threshold cannot be negative")
+		}
+
+		var err error
+		baseline, err = readBaselineFile(*baselineFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		results reqtest.Results
+		err     error
+	)
+
+	if len(args) > 1 {
+		results, err = reqtest.SendTargets(ctx, cfg, args, *parallelTargets)
+	} else {
+		results, err = reqtest.Send(ctx, cfg)
+	}
+
+	if cfg.DryRun {
+		return err
+	}
+
+	switch {
+	case *sendOutput == outputJSON:
+		if err := writeJSONResults(os.Stdout, results); err != nil {
+			logger.Error(err.Error(), "error", err)
+		}
+	case *sendOutput == outputCSV:
+		if err := writeCSVResults(os.Stdout, results, len(args) > 1, *sendIterations > 1); err != nil {
+			logger.Error(err.Error(), "error", err)
+		}
+	case *sendRepeat > 1 || *sendIterations > 1 || *sendDuration > 0:
+		writeHistogram(os.Stdout, results, *histBuckets)
+	}
+
+	if baseline != nil {
+		// json and csv results are written to stdout, so the comparison is kept out of them
+		w := os.Stdout
+		if *sendOutput != outputText {
+			w = os.Stderr
+		}
+
+		if regressed := writeBaselineDiff(w, baseline, results, *regressionLimit); regressed > 0 {
+			return errors.Join(err, fmt.Errorf("%v sizes regressed by more than %v%% against baseline %v", regressed, *regressionLimit, *baselineFile))
+		}
+	}
+
+	return err
+}
+
+func writeJSONResults(w io.Writer, results reqtest.Results) error {
+	if results == nil {
+		results = reqtest.Results{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(results); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
 	}
 
 	return nil
 }
+
+// writeCSVResults writes a header row and then a row per result with its size, latency in milliseconds, status and error,
+// led by the target it was sent to if withTarget is set and the iteration it was part of if withIteration is set
+func writeCSVResults(w io.Writer, results reqtest.Results, withTarget, withIteration bool) error {
+	cw := csv.NewWriter(w)
+	header := []string{"size", "latency_ms", "status", "error"}
+	if withIteration {
+		header = append([]string{"iteration"}, header...)
+	}
+
+	if withTarget {
+		header = append([]string{"target"}, header...)
+	}
+
+	cw.Write(header)
+	for _, result := range results {
+		status := ""
+		if result.StatusCode != 0 {
+			status = strconv.Itoa(result.StatusCode)
+		}
+
+		latency := strconv.FormatFloat(float64(result.Duration)/float64(time.Millisecond), 'f', 3, 64)
+		row := []string{strconv.Itoa(result.Size), latency, status, result.Error}
+		if withIteration {
+			row = append([]string{strconv.Itoa(result.Iteration)}, row...)
+		}
+
+		if withTarget {
+			row = append([]string{result.Target}, row...)
+		}
+
+		cw.Write(row)
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	return nil
+}
+
+// readPayloadFile reads the payload at path, or from stdin if path is -
+func readPayloadFile(path string) ([]byte, error) {
+	if path == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload from stdin: %w", err)
+		}
+
+		return b, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload file: %w", err)
+	}
+
+	return b, nil
+}
+
+// readReplayFile parses the recorded requests in the replay file at path
+func readReplayFile(path string) ([]reqtest.ReplayRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file: %w", err)
+	}
+
+	defer f.Close()
+	return reqtest.ParseReplay(f)
+}
+
+// readSizesFile parses the payload sizes in the sizes file at path
+func readSizesFile(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sizes file: %w", err)
+	}
+
+	defer f.Close()
+	return reqtest.ParseSizes(f)
+}
+
+// readRoutesFile parses the routes in the routes file at path
+func readRoutesFile(path string) (map[string]reqtest.Route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open routes file: %w", err)
+	}
+
+	defer f.Close()
+	return reqtest.ParseRoutes(f)
+}
+
+// readResponsesFile parses the responses in the responses file at path
+func readResponsesFile(path string) ([]reqtest.Route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open responses file: %w", err)
+	}
+
+	defer f.Close()
+	return reqtest.ParseResponses(f)
+}
+
+// isFlagSet reports whether the named flag was explicitly provided on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+
+	"requestechoer/reqtest"
+)
+
+// queryVar defines a repeatable query parameter flag with the given name and usage
+func queryVar(name, usage string) *queryFlag {
+	q := &queryFlag{}
+	flag.Var(q, name, usage)
+	return q
+}
+
+// queryFlag collects repeated "key=value" flags into query parameters
+type queryFlag struct {
+	query url.Values
+}
+
+func (q *queryFlag) String() string {
+	if q == nil || q.query == nil {
+		return ""
+	}
+
+	parts := make([]string, 0, len(q.query))
+	for key, values := range q.query {
+		for _, value := range values {
+			parts = append(parts, key+"="+value)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (q *queryFlag) Set(value string) error {
+	key, val, err := reqtest.ParseQueryParam(value)
+	if err != nil {
+		return err
+	}
+
+	if q.query == nil {
+		q.query = make(url.Values)
+	}
+
+	q.query.Add(key, val)
+	return nil
+}
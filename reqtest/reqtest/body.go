@@ -0,0 +1,64 @@
+package reqtest
+
+import (
+	"io"
+)
+
+// countingWriter counts the bytes written to it without retaining them
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// prefixBuffer retains up to limit bytes written to it and discards the rest
+type prefixBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - len(b.buf); remaining > 0 {
+		b.buf = append(b.buf, p[:min(len(p), remaining)]...)
+	}
+
+	return len(p), nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// repeatingReader endlessly produces repeatingBlock, so large bodies can be streamed without holding them in memory
+type repeatingReader struct {
+	off int
+}
+
+func (r *repeatingReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], repeatingBlock[r.off:])
+		n += c
+		r.off = (r.off + c) % len(repeatingBlock)
+	}
+
+	return n, nil
+}
+
+var (
+	_ io.Reader = (*countingReader)(nil)
+	_ io.Reader = (*repeatingReader)(nil)
+	_ io.Writer = (*countingWriter)(nil)
+	_ io.Writer = (*prefixBuffer)(nil)
+)
@@ -0,0 +1,50 @@
+package reqtest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// errBudgetExhausted is returned for a request that would exceed the total byte budget, after which no more requests are sent
+var errBudgetExhausted = errors.New("max-total-bytes budget exhausted")
+
+// byteBudget caps the total body bytes sent across every request, including retries. It is shared by concurrent workers,
+// and a nil budget is unlimited
+type byteBudget struct {
+	logger    *slog.Logger
+	limit     int64
+	used      atomic.Int64
+	exhausted atomic.Bool
+}
+
+// reserve counts n bytes against the budget, returning false without counting them if they would exceed it.
+// Once a reservation fails every later one does too, so sending stops at the first request that doesn't fit
+func (b *byteBudget) reserve(n int64) bool {
+	if b == nil {
+		return true
+	}
+
+	for !b.exhausted.Load() {
+		used := b.used.Load()
+		if used+n > b.limit {
+			if !b.exhausted.Swap(true) {
+				b.logger.Warn(fmt.Sprintf("hit max-total-bytes budget of %v bytes after sending %v bytes, not sending a request of %v bytes", b.limit, used, n), "max_total_bytes", b.limit, "sent", used, "size", n)
+			}
+
+			return false
+		}
+
+		if b.used.CompareAndSwap(used, used+n) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExhausted reports whether a request has been refused for exceeding the budget
+func (b *byteBudget) isExhausted() bool {
+	return b != nil && b.exhausted.Load()
+}
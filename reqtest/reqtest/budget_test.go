@@ -0,0 +1,67 @@
+package reqtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxTotalBytesCountsCompressedBodies(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	// a 64KiB body of zeros compresses to well under 1KiB, so 4KiB fits every compressed body but not one uncompressed
+	results, err := Send(context.Background(), SendConfig{
+		Address:       server.URL,
+		Sizes:         []int{64 << 10, 64 << 10, 64 << 10},
+		Pattern:       PatternZeros,
+		Gzip:          true,
+		MaxTotalBytes: 4 << 10,
+		Logger:        discardLogger(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := requests.Load(); n != 3 || len(results) != 3 {
+		t.Errorf("sent %v requests with %v results, want every compressed body to fit the budget", n, len(results))
+	}
+}
+
+func TestMaxTotalBytesKeepsRetriedFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// the budget fits the first attempt but no retry
+	results, err := Send(context.Background(), SendConfig{
+		Address:       server.URL,
+		Sizes:         []int{1024},
+		Retries:       3,
+		RetryBackoff:  time.Millisecond,
+		MaxTotalBytes: 1024,
+		Logger:        discardLogger(),
+	})
+	if err == nil {
+		t.Fatal("got no error for a failed request")
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("got %v results, want 1", len(results))
+	}
+
+	if result := results[0]; result.StatusCode != http.StatusServiceUnavailable || strings.Contains(result.Error, "max-total-bytes") {
+		t.Errorf("got status %v with error %q, want the failure of the attempt that was sent", result.StatusCode, result.Error)
+	}
+}
@@ -0,0 +1,109 @@
+package reqtest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// The kinds of failure a request's error is classified as in Result.ErrorKind
+const (
+	ErrorKindDNS               = "dns"
+	ErrorKindConnectionRefused = "connection refused"
+	ErrorKindConnectionReset   = "connection reset"
+	ErrorKindClosed            = "connection closed"
+	ErrorKindTimeout           = "timeout"
+	ErrorKindTLS               = "tls"
+	ErrorKindStatus            = "status"
+	ErrorKindMismatch          = "mismatch"
+	ErrorKindOther             = "other"
+)
+
+// statusError is returned when a response has a status other than those expected
+type statusError struct {
+	code     int
+	expected []int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("did not get %v response, got %v", formatStatusCodes(e.expected), e.code)
+}
+
+// formatStatusCodes joins codes for messages, such as "200, 201, or 204"
+func formatStatusCodes(codes []int) string {
+	formatted := make([]string, len(codes))
+	for i, code := range codes {
+		formatted[i] = strconv.Itoa(code)
+	}
+
+	switch len(formatted) {
+	case 1:
+		return formatted[0]
+	case 2:
+		return formatted[0] + " or " + formatted[1]
+	default:
+		return strings.Join(formatted[:len(formatted)-1], ", ") + ", or " + formatted[len(formatted)-1]
+	}
+}
+
+// classifyError returns the kind of failure err describes. A reset is the peer aborting the connection, while a closed
+// connection is the peer shutting it down cleanly before a complete response was read, such as a dropped connection
+func classifyError(err error) string {
+	var (
+		dnsErr       *net.DNSError
+		statusErr    *statusError
+		netErr       net.Error
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &statusErr):
+		return ErrorKindStatus
+	case errors.Is(err, ErrIntegrityMismatch):
+		return ErrorKindMismatch
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ErrorKindTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorKindConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorKindConnectionReset
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorKindTimeout
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorKindClosed
+	default:
+		return ErrorKindOther
+	}
+}
+
+// kindCounts formats how many of errs are of each kind, such as "connection reset: 2, timeout: 1"
+func kindCounts(errs []error) string {
+	counts := make(map[string]int)
+	for _, err := range errs {
+		counts[classifyError(err)]++
+	}
+
+	parts := make([]string, 0, len(counts))
+	for _, kind := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%v: %v", kind, counts[kind]))
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,110 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// sendConcurrently sends each size repeat times, spread across concurrency workers pulling sizes from a shared channel.
+// No further sizes are handed to workers after the first failed request unless continueOnError is set, though requests
+// already in flight complete, and the returned error lists each size that had a failed request.
+// If ctx is cancelled no further sizes are handed to workers, and requests interrupted by the cancellation are not counted as failures.
+func (s *sender) sendConcurrently(ctx context.Context, sizes []int, repeat, concurrency int, continueOnError bool) (Results, error) {
+	// jobsCtx is cancelled to stop handing out sizes after a failure, leaving requests in flight to complete
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for _, size := range sizes {
+			for i := 0; i < repeat; i++ {
+				if s.budget.isExhausted() {
+					return
+				}
+
+				select {
+				case jobs <- size:
+				case <-jobsCtx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make(Results, 0, len(sizes)*repeat)
+		failures = make(sizeFailures)
+		finished = make(map[int]int)
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			first := true
+			for size := range jobs {
+				if !first {
+					s.pause(jobsCtx)
+				}
+
+				// a size can be handed out as jobs are stopped, or while pausing
+				if jobsCtx.Err() != nil && ctx.Err() == nil {
+					continue
+				}
+
+				first = false
+				s.logger.Info(fmt.Sprintf("worker %v sending %v bytes", worker, size), "worker", worker, "size", size)
+				result, err := s.send(ctx, size)
+				if errors.Is(err, errBudgetExhausted) {
+					continue
+				}
+
+				if err != nil && ctx.Err() != nil {
+					s.logger.Warn(fmt.Sprintf("worker %v request of %v bytes cancelled", worker, size), "worker", worker, "size", size)
+					continue
+				}
+
+				if err != nil {
+					s.logger.Error(fmt.Sprintf("worker %v request of %v bytes failed: %v", worker, size, err), "worker", worker, "size", size, "status", result.StatusCode, "error", err, "error_kind", result.ErrorKind)
+					result.Error = err.Error()
+				} else {
+					s.logger.Info(fmt.Sprintf("worker %v sent %v bytes in %s", worker, size, result.Duration), "worker", worker, "size", size, "status", result.StatusCode, "duration", result.Duration)
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				finished[size]++
+				if err != nil {
+					failures[size] = append(failures[size], err)
+					if !continueOnError {
+						stopJobs()
+					}
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil {
+		completed := 0
+		for _, size := range sizes {
+			if finished[size] == repeat {
+				completed++
+			}
+		}
+
+		return results, cancelledError(ctx, completed, len(sizes))
+	}
+
+	if len(failures) == 0 {
+		return results, nil
+	}
+
+	failures.logSummary(s.logger, len(sizes), repeat)
+	return results, failures.err(repeat)
+}
@@ -0,0 +1,58 @@
+package reqtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendConcurrentlyFailFast(t *testing.T) {
+	const (
+		concurrency = 2
+		sizes       = 20
+	)
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+	}{
+		{name: "fail fast"},
+		{name: "continue on error", continueOnError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			cfg := SendConfig{
+				Address:         server.URL,
+				Concurrency:     concurrency,
+				ContinueOnError: tt.continueOnError,
+				Logger:          discardLogger(),
+			}
+
+			for i := 0; i < sizes; i++ {
+				cfg.Sizes = append(cfg.Sizes, i+1)
+			}
+
+			results, err := Send(context.Background(), cfg)
+			if err == nil {
+				t.Fatal("got no error for failed requests")
+			}
+
+			switch {
+			case tt.continueOnError && len(results) != sizes:
+				t.Errorf("got %v results, want every one of %v sizes sent", len(results), sizes)
+			case !tt.continueOnError && len(results) > concurrency:
+				// each worker stops after the request it has in flight when the first fails
+				t.Errorf("got %v results, want at most one per worker", len(results))
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http/httptrace"
+	"sync"
+)
+
+// connStats tracks each connection requests get from and return to the transport's pool, from httptrace events, to show
+// how the pool behaves under concurrency. Idle connections the transport closes without a request noticing, such as
+// after its idle timeout, are still counted as idle
+type connStats struct {
+	mu        sync.Mutex
+	conns     map[net.Conn]*connState
+	peakInUse int
+	peakIdle  int
+	opened    int
+	reused    int
+}
+
+// connState is how many requests are using a connection, more than one for HTTP/2 streams or a connection handed
+// straight to the next request before the last released it, and whether it has been returned to the idle pool
+type connState struct {
+	requests int
+	idle     bool
+}
+
+// gotConn records a request taking a connection, either newly dialed or reused from the pool
+func (c *connStats) gotConn(info httptrace.GotConnInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info.Reused {
+		c.reused++
+	} else {
+		c.opened++
+	}
+
+	if c.conns == nil {
+		c.conns = make(map[net.Conn]*connState)
+	}
+
+	state := c.conns[info.Conn]
+	if state == nil {
+		state = &connState{}
+		c.conns[info.Conn] = state
+	}
+
+	state.requests++
+	state.idle = false
+	c.updatePeaks()
+}
+
+// putIdleConn records a request's connection being returned to the idle pool, or being closed by the transport when err is set
+func (c *connStats) putIdleConn(conn net.Conn, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.conns[conn]
+	if state == nil {
+		return
+	}
+
+	state.idle = err == nil
+	c.updatePeaks()
+}
+
+// release records a request that got conn being done with it. Connections no request is using that weren't returned to
+// the pool have been closed, so they're forgotten
+func (c *connStats) release(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.conns[conn]
+	if state == nil {
+		return
+	}
+
+	state.requests--
+	if state.requests <= 0 && !state.idle {
+		delete(c.conns, conn)
+	}
+}
+
+// current returns how many connections are in use by requests and how many are idle in the pool
+func (c *connStats) current() (inUse, idle int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts()
+}
+
+func (c *connStats) counts() (inUse, idle int) {
+	for _, state := range c.conns {
+		switch {
+		case state.requests > 0:
+			inUse++
+		case state.idle:
+			idle++
+		}
+	}
+
+	return inUse, idle
+}
+
+func (c *connStats) updatePeaks() {
+	inUse, idle := c.counts()
+	c.peakInUse = max(c.peakInUse, inUse)
+	c.peakIdle = max(c.peakIdle, idle)
+}
+
+// logSummary logs the peak connections in use and idle, and how many were opened and reused, warning when the peak in use
+// reached maxConnsPerHost so requests may have queued waiting for a connection
+func (c *connStats) logSummary(logger *slog.Logger, maxConnsPerHost int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logger.Log(context.Background(), LevelSummary, fmt.Sprintf("connections: peak %v in use, peak %v idle, %v opened, %v reused", c.peakInUse, c.peakIdle, c.opened, c.reused),
+		"peak_in_use", c.peakInUse, "peak_idle", c.peakIdle, "opened", c.opened, "reused", c.reused)
+	if maxConnsPerHost > 0 && c.peakInUse >= maxConnsPerHost {
+		logger.Warn(fmt.Sprintf("warning: max-conns-per-host of %v was reached, requests may have waited for a connection", maxConnsPerHost), "max_conns_per_host", maxConnsPerHost, "peak_in_use", c.peakInUse)
+	}
+}
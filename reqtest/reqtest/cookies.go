@@ -0,0 +1,32 @@
+package reqtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// logCookies logs the names of the cookies the jar will send with req, and is called again with the response to log the
+// names of any cookies it set. Values are not logged as they are often session credentials
+func (s *sender) logCookies(size int, req *http.Request, resp *http.Response) {
+	if resp == nil {
+		if names := cookieNames(s.client.Jar.Cookies(req.URL)); names != "" {
+			s.logger.Info(fmt.Sprintf("request of %v bytes sending cookies %v", size, names), "size", size, "cookies", names)
+		}
+
+		return
+	}
+
+	if names := cookieNames(resp.Cookies()); names != "" {
+		s.logger.Info(fmt.Sprintf("request of %v bytes received cookies %v", size, names), "size", size, "cookies", names)
+	}
+}
+
+func cookieNames(cookies []*http.Cookie) string {
+	names := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		names[i] = cookie.Name
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,71 @@
+package reqtest
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// gunzipResponse replaces the body of a gzip encoded resp with one that decompresses it, logging the compressed and
+// decompressed sizes once the body is closed. Responses that aren't gzip encoded are left as they are
+func gunzipResponse(logger *slog.Logger, resp *http.Response, size int) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return
+	}
+
+	resp.Body = &gunzipBody{logger: logger, size: size, body: resp.Body, compressed: &countingReader{r: resp.Body}}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+// gunzipBody decompresses a gzip response body as it is read, counting the bytes read before and after decompressing
+type gunzipBody struct {
+	logger       *slog.Logger
+	size         int
+	body         io.ReadCloser
+	compressed   *countingReader
+	zr           *gzip.Reader
+	empty        bool
+	decompressed int64
+}
+
+func (g *gunzipBody) Read(p []byte) (int, error) {
+	if g.empty {
+		return 0, io.EOF
+	}
+
+	if g.zr == nil {
+		zr, err := gzip.NewReader(g.compressed)
+		if err == io.EOF && g.compressed.n == 0 {
+			// responses without a body, such as to HEAD requests or with a 204 status, can still be marked gzip encoded
+			g.empty = true
+			return 0, io.EOF
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("could not decompress gzip response: %w", err)
+		}
+
+		g.zr = zr
+	}
+
+	n, err := g.zr.Read(p)
+	g.decompressed += int64(n)
+	return n, err
+}
+
+func (g *gunzipBody) Close() error {
+	if g.zr != nil {
+		ratio := float64(g.compressed.n) / float64(max(g.decompressed, 1))
+		g.logger.Info(fmt.Sprintf("response of request of %v bytes read %v gzip bytes decompressed to %v bytes (%.1f%%)", g.size, g.compressed.n, g.decompressed, ratio*100),
+			"size", g.size, "compressed_size", g.compressed.n, "decompressed_size", g.decompressed, "ratio", ratio)
+	}
+
+	return g.body.Close()
+}
+
+var _ io.ReadCloser = (*gunzipBody)(nil)
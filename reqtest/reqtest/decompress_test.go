@@ -0,0 +1,60 @@
+package reqtest
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendAcceptGzip(t *testing.T) {
+	tests := []struct {
+		name     string
+		respSize int
+		status   int
+		want     string
+	}{
+		{name: "response over the drain limit", respSize: 4 * maxDrainBytes, status: http.StatusOK, want: fmt.Sprintf("decompressed_size=%v", 4*maxDrainBytes)},
+		{name: "empty response", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.WriteHeader(tt.status)
+				if tt.respSize > 0 {
+					zw := gzip.NewWriter(w)
+					zw.Write([]byte(strings.Repeat("x", tt.respSize)))
+					zw.Close()
+				}
+			}))
+			defer server.Close()
+
+			var logs strings.Builder
+			_, err := Send(context.Background(), SendConfig{
+				Address:      server.URL,
+				StartStep:    1,
+				EndStep:      1,
+				AcceptGzip:   true,
+				ExpectStatus: []int{tt.status},
+				Logger:       slog.New(slog.NewTextHandler(&logs, nil)),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(logs.String(), "level=WARN") {
+				t.Errorf("got warnings:\n%v", logs.String())
+			}
+
+			if !strings.Contains(logs.String(), tt.want) {
+				t.Errorf("logs do not contain %v:\n%v", tt.want, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sendForDuration repeatedly sends payloads of size across concurrency workers until duration has elapsed or ctx is cancelled.
+// Requests in flight when the duration elapses are allowed to complete, and requests interrupted by ctx are not recorded.
+func (s *sender) sendForDuration(ctx context.Context, size int, duration time.Duration, concurrency int) (Results, error) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make(Results, 0)
+		failures = 0
+		stats    = &sendStats{}
+	)
+
+	start := time.Now()
+	deadline := start.Add(duration)
+	s.logger.Info(fmt.Sprintf("sending %v bytes repeatedly for %s", size, duration), "size", size, "duration", duration)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ctx.Err() == nil && time.Now().Before(deadline); n++ {
+				if n > 0 {
+					s.pause(ctx)
+					if ctx.Err() != nil || !time.Now().Before(deadline) {
+						return
+					}
+				}
+
+				result, err := s.send(ctx, size)
+				if errors.Is(err, errBudgetExhausted) {
+					return
+				}
+
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+
+				if err != nil {
+					s.logger.Error(fmt.Sprintf("request of %v bytes failed: %v", size, err), "size", size, "status", result.StatusCode, "error", err, "error_kind", result.ErrorKind)
+					result.Error = err.Error()
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				if err != nil {
+					failures++
+				} else {
+					stats.record(size, result.Duration)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	// throughput only counts successful requests, which are what the latency stats cover
+	succeeded := len(stats.latencies)
+	requestsPerSec := float64(succeeded) / elapsed.Seconds()
+	bytesPerSec := float64(stats.totalBytes) / elapsed.Seconds()
+	s.logger.Log(ctx, LevelSummary, fmt.Sprintf("sent %v requests in %s, %v succeeded, %.2f successful requests/s, %.2f bytes/s", len(results), elapsed, succeeded, requestsPerSec, bytesPerSec), "requests", len(results), "succeeded", succeeded, "failed", failures, "duration", elapsed, "requests_per_sec", requestsPerSec, "bytes_per_sec", bytesPerSec)
+	stats.logPercentiles(s.logger, size)
+	if ctx.Err() != nil {
+		return results, fmt.Errorf("cancelled after %s: %w", elapsed, context.Cause(ctx))
+	}
+
+	if failures > 0 {
+		return results, fmt.Errorf("%v of %v requests of %v bytes failed", failures, len(results), size)
+	}
+
+	return results, nil
+}
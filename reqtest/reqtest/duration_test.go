@@ -0,0 +1,55 @@
+package reqtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendForDurationSummary(t *testing.T) {
+	// every other request fails, so attempted and successful requests differ
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if requests.Add(1)%2 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	results, err := Send(context.Background(), SendConfig{
+		Address:         server.URL,
+		Duration:        100 * time.Millisecond,
+		Interval:        time.Millisecond,
+		ContinueOnError: true,
+		Logger:          slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if err == nil {
+		t.Fatal("got no error for failed requests")
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Error == "" {
+			succeeded++
+		}
+	}
+
+	summary := regexp.MustCompile(`requests=(\d+) succeeded=(\d+)`).FindStringSubmatch(logs.String())
+	if summary == nil {
+		t.Fatalf("no summary logged:\n%v", logs.String())
+	}
+
+	if summary[1] != strconv.Itoa(len(results)) || summary[2] != strconv.Itoa(succeeded) {
+		t.Errorf("summary reported %v requests with %v succeeded, want %v with %v succeeded", summary[1], summary[2], len(results), succeeded)
+	}
+}
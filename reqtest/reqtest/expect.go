@@ -0,0 +1,42 @@
+package reqtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// expectContinueTimeout is how long a request with Expect: 100-continue waits for the server to respond before sending its
+// body anyway, for servers that ignore the header
+const expectContinueTimeout = time.Second
+
+// expectContinueTransport returns transport, or a clone of the default transport when nil, waiting expectContinueTimeout
+// for a 100 Continue response
+func expectContinueTransport(transport http.RoundTripper) *http.Transport {
+	t, ok := transport.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	t.ExpectContinueTimeout = expectContinueTimeout
+	return t
+}
+
+// expectsContinue reports whether r is waiting for 100 Continue before sending its body. The server only expects a continue
+// for HTTP/1.1 and later requests with a body, and responds 417 to any other expectation before the request is handled
+func expectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue") && r.ProtoAtLeast(1, 1) && r.ContentLength != 0
+}
+
+// logContinue logs whether the server responded with 100 Continue before the body of a request with Expect: 100-continue was sent
+func (s *sender) logContinue(size int, trace *requestTrace, status int) {
+	switch {
+	case trace.continued:
+		s.logger.Info(fmt.Sprintf("request of %v bytes got 100 Continue before sending the body", size), "size", size, "continued", true, "status", status)
+	case status >= http.StatusMultipleChoices:
+		s.logger.Info(fmt.Sprintf("request of %v bytes was rejected with %v without 100 Continue, the body was not sent", size, status), "size", size, "continued", false, "status", status)
+	default:
+		s.logger.Info(fmt.Sprintf("request of %v bytes got no 100 Continue within %s, the body was sent anyway", size, expectContinueTimeout), "size", size, "continued", false, "status", status)
+	}
+}
@@ -0,0 +1,40 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// sizeFailures collects the errors of failed requests by payload size
+type sizeFailures map[int][]error
+
+func (f sizeFailures) sortedSizes() []int {
+	sizes := make([]int, 0, len(f))
+	for size := range f {
+		sizes = append(sizes, size)
+	}
+
+	slices.Sort(sizes)
+	return sizes
+}
+
+// err returns an error describing each size's failures in ascending size order, or nil if there were none
+func (f sizeFailures) err(repeat int) error {
+	errs := make([]error, 0, len(f))
+	for _, size := range f.sortedSizes() {
+		errs = append(errs, fmt.Errorf("%v of %v requests of %v bytes failed (%v): %w", len(f[size]), repeat, size, kindCounts(f[size]), errors.Join(f[size]...)))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f sizeFailures) logSummary(logger *slog.Logger, totalSizes, repeat int) {
+	logger.Log(context.Background(), LevelSummary, fmt.Sprintf("%v of %v sizes had failed requests:", len(f), totalSizes), "failed_sizes", len(f), "sizes", totalSizes)
+	for _, size := range f.sortedSizes() {
+		kinds := kindCounts(f[size])
+		logger.Log(context.Background(), LevelSummary, fmt.Sprintf("  %v bytes: %v of %v requests failed (%v)", size, len(f[size]), repeat, kinds), "size", size, "failed", len(f[size]), "requests", repeat, "error_kinds", kinds)
+	}
+}
@@ -0,0 +1,49 @@
+package reqtest
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+)
+
+// faultStatuses are the server errors a request failed by fail-rate is randomly responded to with
+var faultStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// injectFault randomly fails or drops a request according to the configured rates, reporting whether a fault was injected.
+// Dropped requests have their body read, up to max-body-bytes, before the connection is closed partway through the response
+func (l *listener) injectFault(w http.ResponseWriter, r *http.Request) bool {
+	switch roll := rand.Float64(); {
+	case roll < l.cfg.FailRate:
+		status := faultStatuses[rand.IntN(len(faultStatuses))]
+		l.logger.Warn(fmt.Sprintf("injecting fault, responding with status %v", status), "fault", "fail", "status", status)
+		w.WriteHeader(status)
+		return true
+	case roll < l.cfg.FailRate+l.cfg.DropRate:
+		l.logger.Warn("injecting fault, dropping connection mid-response", "fault", "drop")
+		io.Copy(io.Discard, r.Body)
+		l.dropConnection(w)
+		return true
+	default:
+		return false
+	}
+}
+
+// dropConnection writes the start of a response that promises more body than is sent, then closes the connection
+func (l *listener) dropConnection(w http.ResponseWriter) {
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// connections that can't be hijacked, such as HTTP/2 streams, are reset by aborting the handler instead
+		l.logger.Warn(fmt.Sprintf("could not hijack connection, aborting response: %v", err), "fault", "drop", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	defer conn.Close()
+	buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 1024\r\n\r\npartial")
+	buf.Flush()
+}
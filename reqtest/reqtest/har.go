@@ -0,0 +1,303 @@
+package reqtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harRecorder accumulates an entry for each request handled and writes them as a HAR 1.2 log. Bodies are recorded as a prefix
+// along with their size and SHA-256 so recording large bodies doesn't hold them in memory
+type harRecorder struct {
+	bodyBytes int
+	mu        sync.Mutex
+	entries   []harEntry
+}
+
+// record wraps next so each request it handles is added to the recorder once next returns
+func (h *harRecorder) record(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		body := &recordedBody{ReadCloser: r.Body, hash: sha256.New(), prefix: &prefixBuffer{limit: h.bodyBytes}}
+		r.Body = body
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rw, r)
+		h.add(newHAREntry(r, rw, body, start, time.Now()))
+	}
+}
+
+func (h *harRecorder) add(entry harEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+}
+
+// write writes the recorded entries to path as a HAR log, ordered by when each request started
+func (h *harRecorder) write(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sort.SliceStable(h.entries, func(i, j int) bool {
+		return h.entries[i].StartedDateTime.Before(h.entries[j].StartedDateTime)
+	})
+
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "reqtest", Version: ReadBuildInfo().Version},
+		Entries: h.entries,
+	}}
+
+	if har.Log.Entries == nil {
+		har.Log.Entries = []harEntry{}
+	}
+
+	b, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode har: %w", err)
+	}
+
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("could not write har: %w", err)
+	}
+
+	return nil
+}
+
+// recordedBody hashes and counts a request body as the handler reads it, keeping only a prefix
+type recordedBody struct {
+	io.ReadCloser
+	hash     hash.Hash
+	prefix   *prefixBuffer
+	n        int64
+	lastRead time.Time
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.hash.Write(p[:n])
+		b.prefix.Write(p[:n])
+		b.n += int64(n)
+	}
+
+	b.lastRead = time.Now()
+	return n, err
+}
+
+// statusRecorder records the status and body size written by a handler and when the response started
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader time.Time
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	// informational responses such as 100 Continue come before the response's actual status
+	if s.wroteHeader.IsZero() && status >= http.StatusOK {
+		s.status = status
+		s.wroteHeader = time.Now()
+	}
+
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.wroteHeader.IsZero() {
+		s.wroteHeader = time.Now()
+	}
+
+	n, err := s.ResponseWriter.Write(p)
+	s.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which echo needs to enable full duplex
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// newHAREntry creates the entry for a request that started at start and finished at end. From the listener's side, send is
+// the time spent reading the body, wait is until the response started, and receive is until the handler returned
+func newHAREntry(r *http.Request, rw *statusRecorder, body *recordedBody, start, end time.Time) harEntry {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	bodyDone := body.lastRead
+	if bodyDone.IsZero() {
+		bodyDone = start
+	}
+
+	// when echoing the response starts before the body is read, so wait is counted from the end of the body
+	respStart := rw.wroteHeader
+	if respStart.Before(bodyDone) {
+		respStart = bodyDone
+	}
+
+	text, encoding := harText(body.prefix.buf)
+	queryString := []harPair{}
+	for name, values := range r.URL.Query() {
+		for _, value := range values {
+			queryString = append(queryString, harPair{Name: name, Value: value})
+		}
+	}
+
+	return harEntry{
+		StartedDateTime: start,
+		Time:            milliseconds(end.Sub(start)),
+		Request: harRequest{
+			Method:      r.Method,
+			URL:         scheme + "://" + r.Host + r.URL.RequestURI(),
+			HTTPVersion: r.Proto,
+			Cookies:     []harPair{},
+			Headers:     harHeaders(r.Header),
+			QueryString: queryString,
+			HeadersSize: -1,
+			BodySize:    body.n,
+			PostData: &harPostData{
+				MimeType:  r.Header.Get("Content-Type"),
+				Text:      text,
+				Encoding:  encoding,
+				SHA256:    hex.EncodeToString(body.hash.Sum(nil)),
+				Truncated: int64(len(body.prefix.buf)) < body.n,
+			},
+		},
+		Response: harResponse{
+			Status:      rw.status,
+			StatusText:  http.StatusText(rw.status),
+			HTTPVersion: r.Proto,
+			Cookies:     []harPair{},
+			Headers:     harHeaders(rw.Header()),
+			Content:     harContent{Size: rw.written, MimeType: rw.Header().Get("Content-Type")},
+			HeadersSize: -1,
+			BodySize:    rw.written,
+		},
+		Cache: struct{}{},
+		Timings: harTimings{
+			Blocked: -1,
+			DNS:     -1,
+			Connect: -1,
+			Send:    milliseconds(bodyDone.Sub(start)),
+			Wait:    milliseconds(respStart.Sub(bodyDone)),
+			Receive: milliseconds(max(end.Sub(respStart), 0)),
+			SSL:     -1,
+		},
+	}
+}
+
+func harHeaders(header http.Header) []harPair {
+	pairs := []harPair{}
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, harPair{Name: name, Value: value})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Name < pairs[j].Name
+	})
+
+	return pairs
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+// harPostData holds a prefix of the body as its text, with the custom _sha256 and _truncated fields describing the whole body
+// harText returns b as the text of HAR post data, base64 encoded when it isn't valid UTF-8 so binary bodies aren't mangled
+// by being written as JSON
+func harText(b []byte) (text, encoding string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+type harPostData struct {
+	MimeType  string `json:"mimeType"`
+	Text      string `json:"text"`
+	Encoding  string `json:"encoding,omitempty"`
+	SHA256    string `json:"_sha256"`
+	Truncated bool   `json:"_truncated"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int64      `json:"headersSize"`
+	BodySize    int64      `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
@@ -0,0 +1,55 @@
+package reqtest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHARReplayBodies(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "text", body: []byte(`{"hello": "world"}`)},
+		{name: "binary", body: []byte{0x00, 0xff, 0xfe, 0x80, 'a', 0xc3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &harRecorder{bodyBytes: 1 << 10}
+			handler := recorder.record(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+			})
+
+			handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/upload", bytes.NewReader(tt.body)))
+			path := filepath.Join(t.TempDir(), "requests.har")
+			if err := recorder.write(path); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer f.Close()
+			requests, err := ParseReplay(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(requests) != 1 {
+				t.Fatalf("got %v requests, want 1", len(requests))
+			}
+
+			if requests[0].Body != string(tt.body) {
+				t.Errorf("replayed body %q, want the recorded body %q", requests[0].Body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package reqtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ParseHeader parses a header in the form "Key: Value"
+func ParseHeader(str string) (string, string, error) {
+	key, value, ok := strings.Cut(str, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid header %q, must be in the form \"Key: Value\"", str)
+	}
+
+	return key, strings.TrimSpace(value), nil
+}
+
+// withResponseHeader sets header on the responses written by next. The header is applied when the final status is written
+// rather than up front so it replaces values next sets itself, and is left off informational responses such as 100 Continue
+func withResponseHeader(header http.Header, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(&headerWriter{ResponseWriter: w, header: header}, r)
+	}
+}
+
+// headerWriter is a http.ResponseWriter that adds header to the response before the status is written
+type headerWriter struct {
+	http.ResponseWriter
+	header  http.Header
+	applied bool
+}
+
+func (h *headerWriter) apply() {
+	if h.applied {
+		return
+	}
+
+	h.applied = true
+	for key, values := range h.header {
+		h.ResponseWriter.Header()[key] = slices.Clone(values)
+	}
+}
+
+func (h *headerWriter) WriteHeader(code int) {
+	if code >= 200 {
+		h.apply()
+	}
+
+	h.ResponseWriter.WriteHeader(code)
+}
+
+func (h *headerWriter) Write(b []byte) (int, error) {
+	h.apply()
+	return h.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which echo needs to enable full duplex
+func (h *headerWriter) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
+
+// defaultReflectPrefix is prepended to the names of reflected request headers when ListenConfig.ReflectPrefix isn't set
+const defaultReflectPrefix = "X-Echo-"
+
+// withReflectedHeaders copies the request headers named in names, or every request header if names is *, into the response
+// under prefix before calling next. Host is included like any other header, as proxies commonly rewrite it
+func withReflectedHeaders(names []string, prefix string, next http.HandlerFunc) http.HandlerFunc {
+	all := slices.Contains(names, "*")
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Clone()
+		header.Set("Host", r.Host)
+		for key, values := range header {
+			if all || slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, key) }) {
+				w.Header()[http.CanonicalHeaderKey(prefix+key)] = values
+			}
+		}
+
+		next(w, r)
+	}
+}
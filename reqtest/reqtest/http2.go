@@ -0,0 +1,31 @@
+package reqtest
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+// newHTTP2Transport creates a transport that only speaks HTTP/2. https addresses negotiate HTTP/2 with TLS, while http and
+// unix domain socket addresses use h2c with prior knowledge, sending HTTP/2 over cleartext without an upgrade
+func newHTTP2Transport(address, unixPath string) *http2.Transport {
+	transport := &http2.Transport{}
+	if unixPath == "" && !strings.HasPrefix(address, "http://") {
+		return transport
+	}
+
+	transport.AllowHTTP = true
+	transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+		var d net.Dialer
+		if unixPath != "" {
+			return d.DialContext(ctx, "unix", unixPath)
+		}
+
+		return d.DialContext(ctx, network, addr)
+	}
+
+	return transport
+}
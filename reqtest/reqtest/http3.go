@@ -0,0 +1,11 @@
+package reqtest
+
+import (
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newHTTP3Transport creates a transport that only speaks HTTP/3 over QUIC. QUIC always runs TLS 1.3, so the transport can only
+// reach https addresses of servers serving HTTP/3 over UDP
+func newHTTP3Transport() *http3.Transport {
+	return &http3.Transport{}
+}
@@ -0,0 +1,27 @@
+package reqtest
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// pause waits between requests for the configured interval, randomized by up to jitter percent either way.
+// It returns early if ctx is done, and immediately if no interval is configured
+func (s *sender) pause(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+
+	d := s.interval
+	if s.jitter > 0 {
+		d = time.Duration(float64(d) * (1 + (rand.Float64()*2-1)*s.jitter/100))
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,59 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// iterate runs the ramp of sizes n times back to back, labelling each result with its iteration and logging the heap
+// and goroutine counts after each so leaks across iterations stand out. Latency stats are aggregated across every
+// iteration once they're done. Failures stop the run unless cfg.ContinueOnError is set, in which case every
+// iteration's error is returned.
+func (s *sender) iterate(ctx context.Context, cfg SendConfig, sizes []int, n int) (Results, error) {
+	var (
+		results Results
+		errs    []error
+		stats   sendStats
+		ran     int
+	)
+
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			s.pause(ctx)
+		}
+
+		if ctx.Err() != nil || s.budget.isExhausted() {
+			break
+		}
+
+		s.logger.Info(fmt.Sprintf("starting iteration %v of %v", i, n), "iteration", i, "iterations", n)
+		ran = i
+		iterResults, err := s.ramp(ctx, cfg, sizes)
+		for j := range iterResults {
+			iterResults[j].Iteration = i
+			if iterResults[j].Error == "" {
+				stats.record(iterResults[j].Size, iterResults[j].Duration)
+			}
+		}
+
+		results = append(results, iterResults...)
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		goroutines := runtime.NumGoroutine()
+		s.logger.Info(fmt.Sprintf("finished iteration %v of %v with %v bytes of heap in use and %v goroutines", i, n, mem.HeapInuse, goroutines), "iteration", i, "iterations", n, "heap_bytes", mem.HeapInuse, "goroutines", goroutines)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("iteration %v: %w", i, err))
+			if !cfg.ContinueOnError || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	p50, p90, p99 := stats.percentile(0.5), stats.percentile(0.9), stats.percentile(0.99)
+	s.logger.Log(context.Background(), LevelSummary, fmt.Sprintf("across %v of %v iterations:", ran, n), "iterations", ran)
+	stats.logSummary(s.logger)
+	s.logger.Log(context.Background(), LevelSummary, fmt.Sprintf("latency p50: %s, p90: %s, p99: %s", p50, p90, p99), "p50", p50, "p90", p90, "p99", p99)
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,37 @@
+package reqtest
+
+import (
+	"fmt"
+	"slices"
+)
+
+// largePayloadBytes is the largest generated payload sent without SendConfig.Force, as larger bodies can exhaust the memory
+// of a listener or saturate a link
+const largePayloadBytes = 256 << 20
+
+// checkLargePayloads refuses a run whose largest generated payload exceeds largePayloadBytes unless it is forced or a dry
+// run, which instead warn with the total bytes the run would send. A Payload or Template is already in memory, so its size
+// was chosen by the caller and isn't checked
+func (s *sender) checkLargePayloads(cfg SendConfig, sizes []int) error {
+	if cfg.Payload != nil || cfg.Template != "" || len(sizes) == 0 || slices.Max(sizes) <= largePayloadBytes {
+		return nil
+	}
+
+	largest := slices.Max(sizes)
+	total := "an unbounded number of bytes until the duration elapses"
+	if cfg.Duration == 0 {
+		var sum int64
+		for _, size := range sizes {
+			sum += int64(size)
+		}
+
+		total = fmt.Sprintf("%v bytes", sum*int64(max(cfg.Repeat, 1))*int64(max(cfg.Iterations, 1)))
+	}
+
+	if !cfg.Force && !cfg.DryRun {
+		return fmt.Errorf("largest payload of %v bytes exceeds %v bytes and the run would send %v in total, use force to send it anyway", largest, largePayloadBytes, total)
+	}
+
+	s.logger.Warn(fmt.Sprintf("warning: largest payload of %v bytes exceeds %v bytes, the run will send %v in total", largest, largePayloadBytes, total), "size", largest, "limit", largePayloadBytes)
+	return nil
+}
@@ -0,0 +1,30 @@
+package reqtest
+
+import "testing"
+
+func TestCheckLargePayloads(t *testing.T) {
+	large := []int{1, largePayloadBytes + 1}
+	tests := []struct {
+		name    string
+		cfg     SendConfig
+		sizes   []int
+		wantErr bool
+	}{
+		{name: "within the limit", sizes: []int{1, largePayloadBytes}},
+		{name: "generated over the limit", sizes: large, wantErr: true},
+		{name: "forced", cfg: SendConfig{Force: true}, sizes: large},
+		{name: "dry run", cfg: SendConfig{DryRun: true}, sizes: large},
+		// the sizes of payloads already in memory are those of the payload itself, so they stand in for one here
+		{name: "payload", cfg: SendConfig{Payload: []byte{}}, sizes: large},
+		{name: "template", cfg: SendConfig{Template: "{{.Index}}"}, sizes: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &sender{logger: discardLogger()}
+			if err := s.checkLargePayloads(tt.cfg, tt.sizes); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,868 @@
+package reqtest
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ListenConfig configures the listener started by Listen
+type ListenConfig struct {
+	// Address is the address to listen on, such as 0.0.0.0:8080, or a unix domain socket such as unix:/tmp/reqtest.sock
+	Address string
+	// Network is the network to listen on, one of tcp, tcp4, or tcp6. Defaults to tcp, and is ignored for unix domain sockets
+	Network string
+	// RawTCP accepts plain connections rather than serving HTTP, counting the bytes read from each until the client closes its
+	// side and writing them back with Echo
+	RawTCP bool
+	// Routes maps http.ServeMux patterns to canned responses. Requests not matching a route are handled as usual
+	Routes map[string]Route
+	// Responses are served in order, one per request not matching a route regardless of its content, looping back to the
+	// first once all have been served
+	Responses []Route
+	// ResponsesStrict serves Responses only once, handling requests as usual after the last one
+	ResponsesStrict bool
+	// RespDelay adds a delay before reading and responding to each request
+	RespDelay time.Duration
+	// Echo writes the received request body back in the response
+	Echo bool
+	// AllowGet handles GET requests like any other method. Otherwise they are answered with 405 and a page describing the listener
+	AllowGet bool
+	// WebSocket accepts WebSocket connections at /ws, echoing each message back. Other paths are handled as usual
+	WebSocket bool
+	// RespSize streams this many bytes of generated data back in each response body, 0 to respond with an empty body
+	RespSize int64
+	// Reflect responds with a JSON description of each request's method, path, query, headers, and body length
+	Reflect bool
+	// Header is set on every response other than health checks just before its status is written, replacing any values
+	// the listener or a route would have sent for the same keys, such as Content-Type
+	Header http.Header
+	// ReflectHeaders are the names of request headers, or * for all of them, copied into each response under ReflectPrefix,
+	// so a client can tell which of the headers it sent were stripped or rewritten on the way
+	ReflectHeaders []string
+	// ReflectPrefix is prepended to the names of reflected request headers. Defaults to X-Echo-
+	ReflectPrefix string
+	// Trailer is sent as trailers after the body of every response other than health checks, making HTTP/1.1 responses
+	// chunked so they can be sent
+	Trailer http.Header
+	// Statuses are the status codes to respond with, cycled through per request. Defaults to 200
+	Statuses []int
+	// MaxBodyBytes is the maximum request body size accepted before responding with 413, 0 for no limit. It applies to
+	// gzip bodies both as sent and once decompressed
+	MaxBodyBytes int64
+	// ReadBuffer is the size in bytes of the buffer request bodies are read through. Defaults to 32KiB, like io.Copy
+	ReadBuffer int
+	// ReadRate limits how fast request bodies, or connections with RawTCP, are read in bytes per second, 0 for no limit
+	ReadRate int64
+	// MaxRequests is the number of requests, or connections with RawTCP, to serve before shutting down, 0 for no limit
+	MaxRequests int64
+	// SaveDir is a directory to save each received request body to
+	SaveDir string
+	// Verbose logs the request line and headers of each request
+	Verbose bool
+	// MaxDumpBytes is the maximum number of body bytes to log when Verbose is set, 0 to not log the body
+	MaxDumpBytes int
+	// TLSCert and TLSKey are paths to a certificate and private key to serve TLS with
+	TLSCert string
+	TLSKey  string
+	// TLSSelfSigned serves TLS with a generated self-signed certificate for localhost
+	TLSSelfSigned bool
+	// ClientCA is a path to PEM encoded CA certificates that clients must present a certificate signed by when serving TLS
+	ClientCA string
+	// TLSMinVersion and TLSMaxVersion are the TLS versions, such as tls.VersionTLS12, to accept when serving TLS. Handshakes
+	// outside them fail. 0 uses crypto/tls's defaults
+	TLSMinVersion uint16
+	TLSMaxVersion uint16
+	// TLSCiphers restricts the cipher suites negotiated when serving TLS 1.2 and earlier. TLS 1.3 suites are not configurable
+	TLSCiphers []uint16
+	// FailRate is the fraction of requests, from 0 to 1, to randomly respond to with a 5xx status
+	FailRate float64
+	// DropRate is the fraction of requests, from 0 to 1, to randomly close the connection of partway through the response
+	DropRate float64
+	// Hash returns the SHA-256 of each received body in the X-Body-SHA256 response header, or trailer when echoing
+	Hash bool
+	// ReadTimeout, WriteTimeout, and IdleTimeout set the matching http.Server timeouts, 0 for no limit. ReadTimeout covers reading
+	// the headers and body, WriteTimeout runs from the end of reading the headers to the end of writing the response, and
+	// IdleTimeout is how long a keep-alive connection may wait for its next request, defaulting to ReadTimeout like http.Server
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long to wait for in-flight requests to complete when shutting down
+	ShutdownTimeout time.Duration
+	// HARFile records received requests to a HAR 1.2 file written on graceful shutdown
+	HARFile string
+	// HARBodyBytes is the number of body bytes to record in HARFile per request, alongside each body's size and SHA-256.
+	// Bodies that aren't valid UTF-8 are recorded base64 encoded
+	HARBodyBytes int
+	// LogFile appends a JSON object per request with its time, method, path, remote address, body size, status, and
+	// duration in milliseconds to a file as each request completes
+	LogFile string
+	// PprofAddress serves net/http/pprof handlers on a separate address from requests when set
+	PprofAddress string
+	// MetricsAddress serves Prometheus metrics of handled requests at /metrics on a separate address from requests when set
+	MetricsAddress string
+	// StatsSignal logs a snapshot of the total requests and body bytes, requests in flight, and uptime each time it receives,
+	// without shutting down. Nil to never log snapshots
+	StatsSignal <-chan os.Signal
+	// Logger receives the listener's logs. Defaults to text logs on stderr
+	Logger *slog.Logger
+}
+
+// Listen serves requests according to cfg until ctx is done or MaxRequests have been served, then shuts down gracefully
+func Listen(ctx context.Context, cfg ListenConfig) error {
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return errors.New("tls-cert and tls-key must both be provided to serve TLS")
+	}
+
+	if cfg.Echo && cfg.Reflect {
+		return errors.New("echo and reflect cannot both be used")
+	}
+
+	if cfg.ReadBuffer < 0 {
+		return errors.New("read-buffer cannot be negative")
+	}
+
+	if cfg.RespSize < 0 {
+		return errors.New("resp-size cannot be negative")
+	}
+
+	if cfg.RespSize > 0 && (cfg.Echo || cfg.Reflect) {
+		return errors.New("resp-size cannot be used with echo or reflect")
+	}
+
+	if cfg.TLSSelfSigned && cfg.TLSCert != "" {
+		return errors.New("tls-self-signed cannot be used with tls-cert and tls-key")
+	}
+
+	if cfg.ClientCA != "" && cfg.TLSCert == "" && !cfg.TLSSelfSigned {
+		return errors.New("client-ca requires serving TLS with tls-cert and tls-key or tls-self-signed")
+	}
+
+	tlsPolicy := cfg.TLSMinVersion != 0 || cfg.TLSMaxVersion != 0 || len(cfg.TLSCiphers) > 0
+	if tlsPolicy && cfg.TLSCert == "" && !cfg.TLSSelfSigned {
+		return errors.New("tls-min-version, tls-max-version, and tls-ciphers require serving TLS with tls-cert and tls-key or tls-self-signed")
+	}
+
+	if cfg.TLSMinVersion != 0 && cfg.TLSMaxVersion != 0 && cfg.TLSMinVersion > cfg.TLSMaxVersion {
+		return errors.New("tls-min-version cannot be greater than tls-max-version")
+	}
+
+	if cfg.FailRate < 0 || cfg.DropRate < 0 || cfg.FailRate+cfg.DropRate > 1 {
+		return errors.New("fail-rate and drop-rate must be between 0 and 1, and add up to at most 1")
+	}
+
+	if err := validateNetwork(cfg.Network, cfg.Address); err != nil {
+		return err
+	}
+
+	if cfg.RawTCP && (cfg.Reflect || len(cfg.Routes) > 0 || len(cfg.Responses) > 0 || cfg.TLSCert != "" || cfg.TLSSelfSigned || cfg.HARFile != "" || cfg.LogFile != "" || cfg.MetricsAddress != "" || len(cfg.Header) > 0 || len(cfg.Trailer) > 0 || len(cfg.ReflectHeaders) > 0 || cfg.WebSocket) {
+		return errors.New("raw-tcp cannot be used with reflect, routes, responses, tls, har, log-file, metrics, resp-header, trailer, reflect-headers, or websocket")
+	}
+
+	if cfg.ResponsesStrict && len(cfg.Responses) == 0 {
+		return errors.New("responses-strict requires responses")
+	}
+
+	if strings.ContainsAny(cfg.ReflectPrefix, " \t:") {
+		return fmt.Errorf("invalid reflect-prefix %q, must be usable in a header name", cfg.ReflectPrefix)
+	}
+
+	if cfg.RawTCP && (cfg.MaxBodyBytes > 0 || cfg.SaveDir != "" || cfg.Hash || cfg.FailRate > 0 || cfg.DropRate > 0 || cfg.Verbose || cfg.RespDelay > 0 || cfg.RespSize > 0) {
+		return errors.New("raw-tcp cannot be used with max-body-bytes, save-dir, hash, fail-rate, drop-rate, verbose, resp-delay, or resp-size")
+	}
+
+	if cfg.ReadTimeout < 0 || cfg.WriteTimeout < 0 || cfg.IdleTimeout < 0 {
+		return errors.New("read-timeout, write-timeout, and idle-timeout cannot be negative")
+	}
+
+	if cfg.RawTCP && (cfg.ReadTimeout > 0 || cfg.WriteTimeout > 0 || cfg.IdleTimeout > 0) {
+		return errors.New("raw-tcp cannot be used with read-timeout, write-timeout, or idle-timeout")
+	}
+
+	if cfg.SaveDir != "" {
+		if err := os.MkdirAll(cfg.SaveDir, 0o755); err != nil {
+			return fmt.Errorf("could not create save-dir: %w", err)
+		}
+	}
+
+	if len(cfg.Statuses) == 0 {
+		cfg.Statuses = []int{http.StatusOK}
+	}
+
+	if cfg.ReadBuffer == 0 {
+		cfg.ReadBuffer = defaultReadBuffer
+	}
+
+	if cfg.ReflectPrefix == "" {
+		cfg.ReflectPrefix = defaultReflectPrefix
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = defaultLogger()
+	}
+
+	l := &listener{
+		cfg:               cfg,
+		logger:            cfg.Logger,
+		statuses:          &statusRotation{statuses: cfg.Statuses},
+		maxRequestsServed: make(chan struct{}),
+		started:           time.Now(),
+	}
+
+	l.logger.Info(fmt.Sprintf("reading request bodies with a %v byte buffer", cfg.ReadBuffer), "read_buffer", cfg.ReadBuffer)
+	if cfg.RawTCP {
+		return l.listenRaw(ctx)
+	}
+
+	mux := http.NewServeMux()
+	wrap := func(h http.HandlerFunc) http.HandlerFunc { return h }
+	if len(cfg.Header) > 0 {
+		wrap = func(h http.HandlerFunc) http.HandlerFunc { return withResponseHeader(cfg.Header, h) }
+	}
+
+	if len(cfg.ReflectHeaders) > 0 {
+		header := wrap
+		wrap = func(h http.HandlerFunc) http.HandlerFunc {
+			return header(withReflectedHeaders(cfg.ReflectHeaders, cfg.ReflectPrefix, h))
+		}
+		l.logger.Info(fmt.Sprintf("reflecting request headers %v into responses with prefix %v", strings.Join(cfg.ReflectHeaders, ", "), cfg.ReflectPrefix), "reflect_headers", cfg.ReflectHeaders, "reflect_prefix", cfg.ReflectPrefix)
+	}
+
+	if len(cfg.Trailer) > 0 {
+		header := wrap
+		wrap = func(h http.HandlerFunc) http.HandlerFunc { return withTrailer(cfg.Trailer, header(h)) }
+		keys := slices.Sorted(maps.Keys(cfg.Trailer))
+		l.logger.Info(fmt.Sprintf("sending trailers %v after each response body", strings.Join(keys, ", ")), "trailers", keys)
+	}
+
+	var recorder *harRecorder
+	if cfg.HARFile != "" {
+		recorder = &harRecorder{bodyBytes: cfg.HARBodyBytes}
+		header := wrap
+		wrap = func(h http.HandlerFunc) http.HandlerFunc { return recorder.record(header(h)) }
+	}
+
+	var requests *requestLog
+	if cfg.LogFile != "" {
+		var err error
+		requests, err = openRequestLog(cfg.LogFile, l.logger)
+		if err != nil {
+			return err
+		}
+
+		defer requests.close()
+		logged := wrap
+		wrap = func(h http.HandlerFunc) http.HandlerFunc { return requests.record(logged(h)) }
+	}
+
+	var metrics *listenerMetrics
+	if cfg.MetricsAddress != "" {
+		metrics = newListenerMetrics()
+		record := wrap
+		wrap = func(h http.HandlerFunc) http.HandlerFunc { return metrics.instrument(record(h)) }
+	}
+
+	handler := l.handle
+	if len(cfg.Responses) > 0 {
+		handler = l.sequenceHandler(&responseSequence{responses: cfg.Responses, strict: cfg.ResponsesStrict}, l.handle)
+	}
+
+	mux.HandleFunc("/", wrap(handler))
+	mux.HandleFunc("/healthz", healthz)
+	if cfg.WebSocket {
+		mux.HandleFunc(websocketPath, l.websocketHandler())
+	}
+
+	if err := l.registerRoutes(mux, cfg.Routes, wrap); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         cfg.Address,
+		Handler:      mux,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	if cfg.ReadTimeout > 0 || cfg.WriteTimeout > 0 || cfg.IdleTimeout > 0 {
+		l.logger.Info(fmt.Sprintf("timeouts: read %s, write %s, idle %s (0s for no limit)", cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout), "read_timeout", cfg.ReadTimeout, "write_timeout", cfg.WriteTimeout, "idle_timeout", cfg.IdleTimeout)
+	}
+
+	if cfg.TLSSelfSigned {
+		cert, fp, err := generateSelfSignedCert()
+		if err != nil {
+			return fmt.Errorf("could not generate self-signed certificate: %w", err)
+		}
+
+		l.logger.Info(fmt.Sprintf("generated self-signed certificate with SHA-256 fingerprint %v", fp), "fingerprint", fp)
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	if cfg.ClientCA != "" {
+		if err := l.requireClientCerts(server, cfg.ClientCA); err != nil {
+			return err
+		}
+	}
+
+	if tlsPolicy {
+		l.applyTLSPolicy(server, cfg.TLSMinVersion, cfg.TLSMaxVersion, cfg.TLSCiphers)
+	}
+
+	snapshotCtx, stopSnapshots := context.WithCancel(ctx)
+	defer stopSnapshots()
+	go l.logSnapshots(snapshotCtx, cfg.StatsSignal)
+
+	errCh := make(chan error, 3)
+	go func() {
+		errCh <- l.serve(server)
+	}()
+
+	var pprofServer, metricsServer *http.Server
+	if cfg.PprofAddress != "" {
+		pprofServer = l.serveAux("pprof", cfg.PprofAddress, pprofMux(), errCh)
+	}
+
+	if metrics != nil {
+		metricsServer = l.serveAux("metrics", cfg.MetricsAddress, metrics.handler(), errCh)
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	case <-l.maxRequestsServed:
+		l.logger.Info(fmt.Sprintf("served max-requests of %v", cfg.MaxRequests), "max_requests", cfg.MaxRequests)
+	}
+
+	pending := l.inFlight.Load()
+	l.logger.Info(fmt.Sprintf("shutting down, waiting up to %s for %v in-flight requests...", cfg.ShutdownTimeout, pending), "timeout", cfg.ShutdownTimeout, "in_flight", pending)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if pprofServer != nil {
+		// profiles such as cpu can run for a long time, so they are cut off rather than waited for
+		pprofServer.Close()
+	}
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		remaining := l.inFlight.Load()
+		l.logger.Warn(fmt.Sprintf("%v of %v in-flight requests did not complete within shutdown-timeout of %s, forcibly terminating them", remaining, pending, cfg.ShutdownTimeout), "in_flight", pending, "remaining", remaining, "timeout", cfg.ShutdownTimeout)
+		server.Close()
+		l.websockets.closeAll()
+		return fmt.Errorf("failed to shut down cleanly: %w", err)
+	}
+
+	// hijacked connections such as WebSockets aren't waited for or closed by Shutdown, so they are closed here
+	remaining := l.inFlight.Load()
+	if closed := l.websockets.closeAll(); closed > 0 {
+		l.logger.Warn(fmt.Sprintf("forcibly closed %v WebSocket connections still open", closed), "closed", closed)
+	}
+
+	if drained := pending - remaining; drained > 0 {
+		l.logger.Info(fmt.Sprintf("drained %v in-flight requests", drained), "drained", drained)
+	}
+
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+
+	if requests != nil {
+		if err := requests.close(); err != nil {
+			return err
+		}
+	}
+
+	if recorder != nil {
+		if err := recorder.write(cfg.HARFile); err != nil {
+			return err
+		}
+
+		l.logger.Info(fmt.Sprintf("recorded %v requests to %v", len(recorder.entries), cfg.HARFile), "requests", len(recorder.entries), "path", cfg.HARFile)
+	}
+
+	if path, ok := unixSocketPath(cfg.Address); ok {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("could not remove unix socket: %w", err)
+		}
+	}
+
+	l.logger.Info("shut down cleanly")
+	return nil
+}
+
+// bodyHashHeader is the response header or trailer the SHA-256 of the received body is returned in when hashing
+const bodyHashHeader = "X-Body-SHA256"
+
+// validateNetwork checks network is a supported tcp network and that an IP literal host in address belongs to its family
+func validateNetwork(network, address string) error {
+	if _, ok := unixSocketPath(address); ok {
+		if network != "" && network != "tcp" {
+			return errors.New("network cannot be used with a unix socket address")
+		}
+
+		return nil
+	}
+
+	if network == "" {
+		return nil
+	}
+
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return fmt.Errorf("invalid network %v, must be one of: tcp, tcp4, tcp6", network)
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+	case network == "tcp4" && ip.To4() == nil:
+		return fmt.Errorf("address %v is not an IPv4 address, required by network tcp4", address)
+	case network == "tcp6" && ip.To4() != nil:
+		return fmt.Errorf("address %v is not an IPv6 address, required by network tcp6", address)
+	}
+
+	return nil
+}
+
+// serveAux serves handler on address in the background, separate from the server handling requests, sending any failure to errCh
+func (l *listener) serveAux(name, address string, handler http.Handler, errCh chan<- error) *http.Server {
+	server := &http.Server{Addr: address, Handler: handler}
+	go func() {
+		l.logger.Info(fmt.Sprintf("serving %v on %v", name, address), "address", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("could not serve %v: %w", name, err)
+		}
+	}()
+
+	return server
+}
+
+// pprofMux registers the net/http/pprof handlers on their own mux so profiling never shares the request handling mux
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// listener holds the state shared across requests handled by Listen
+type listener struct {
+	cfg               ListenConfig
+	logger            *slog.Logger
+	statuses          *statusRotation
+	served            atomic.Int64
+	maxRequestsServed chan struct{}
+	started           time.Time
+	requests          atomic.Int64
+	bytes             atomic.Int64
+	inFlight          atomic.Int64
+	websockets        websocketConns
+}
+
+// serve blocks serving on the server's address, using TLS if the server has a TLS config or a cert and key are provided
+func (l *listener) serve(server *http.Server) error {
+	ln, err := net.Listen(listenNetwork(l.cfg.Network, server.Addr))
+	if err != nil {
+		return fmt.Errorf("could not listen: %w", err)
+	}
+
+	if server.TLSConfig != nil || l.cfg.TLSCert != "" {
+		l.logger.Info(fmt.Sprintf("listening with TLS on %v", server.Addr), "address", server.Addr, "tls", true)
+		return server.ServeTLS(ln, l.cfg.TLSCert, l.cfg.TLSKey)
+	}
+
+	l.logger.Info(fmt.Sprintf("listening on %v", server.Addr), "address", server.Addr)
+	return server.Serve(ln)
+}
+
+// listenNetwork returns the network and address to pass to net.Listen for the configured network and address
+func listenNetwork(network, address string) (string, string) {
+	if path, ok := unixSocketPath(address); ok {
+		return "unix", path
+	}
+
+	if network == "" {
+		network = "tcp"
+	}
+
+	return network, address
+}
+
+// healthz answers liveness probes without logging or counting towards max-requests
+func healthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// admit counts a request towards max-requests, responding with 503 and returning false once max-requests have been served.
+// done must be called once an admitted request has been handled, so the listener only shuts down after the last request
+func (l *listener) admit(w http.ResponseWriter) (done func(), ok bool) {
+	n := l.served.Add(1)
+	if l.cfg.MaxRequests > 0 {
+		if n > l.cfg.MaxRequests {
+			l.logger.Warn(fmt.Sprintf("rejecting request, already served max-requests of %v", l.cfg.MaxRequests), "status", http.StatusServiceUnavailable, "max_requests", l.cfg.MaxRequests)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return nil, false
+		}
+
+		if n == l.cfg.MaxRequests {
+			return func() { close(l.maxRequestsServed) }, true
+		}
+	}
+
+	return func() {}, true
+}
+
+// getInfo is the body of the 405 response to GET requests when they are not allowed
+const getInfo = `This is reqtest, listening for requests to echo to its logs.
+GET requests are not handled unless the listener is started with allow-get.
+Send a request with a body instead, such as reqtest send <address> or curl -X PUT --data-binary @file <address>.
+`
+
+// rejectGet responds to a GET request with 405 and a page describing the listener, rather than treating it as a request to echo
+func (l *listener) rejectGet(w http.ResponseWriter, r *http.Request) {
+	l.logger.Warn(fmt.Sprintf("rejecting GET request for %v with 405, use allow-get to handle GET requests", r.URL.Path), "method", r.Method, "path", r.URL.Path, "status", http.StatusMethodNotAllowed)
+	w.Header().Set("Allow", "HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	io.WriteString(w, getInfo)
+}
+
+func (l *listener) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && !l.cfg.AllowGet {
+		l.rejectGet(w, r)
+		return
+	}
+
+	done, ok := l.admit(w)
+	if !ok {
+		return
+	}
+
+	defer done()
+	defer l.checkWriteTimeout(time.Now())
+	l.requests.Add(1)
+	l.inFlight.Add(1)
+	defer l.inFlight.Add(-1)
+	l.logger.Info("received request", "method", r.Method, "path", r.URL.Path, "content_length", r.ContentLength)
+	if l.cfg.Verbose {
+		dump, err := httputil.DumpRequest(r, false)
+		if err != nil {
+			l.logger.Error(fmt.Sprintf("error dumping request: %v", err), "error", err)
+		} else {
+			l.logger.Info(fmt.Sprintf("request:\n%s", dump))
+		}
+	}
+
+	expect := expectsContinue(r)
+	if l.cfg.MaxBodyBytes > 0 {
+		if r.ContentLength > l.cfg.MaxBodyBytes && expect {
+			l.logger.Warn(fmt.Sprintf("rejecting Expect: 100-continue request with content length %v without reading the body, exceeds max-body-bytes of %v", r.ContentLength, l.cfg.MaxBodyBytes), "status", http.StatusRequestEntityTooLarge, "content_length", r.ContentLength, "max_body_bytes", l.cfg.MaxBodyBytes, "continued", false)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if r.ContentLength > l.cfg.MaxBodyBytes {
+			l.logger.Warn(fmt.Sprintf("rejecting request with content length %v, exceeds max-body-bytes of %v", r.ContentLength, l.cfg.MaxBodyBytes), "status", http.StatusRequestEntityTooLarge, "content_length", r.ContentLength, "max_body_bytes", l.cfg.MaxBodyBytes)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, l.cfg.MaxBodyBytes)
+	}
+
+	// faults are injected once the body is limited, as dropped requests read it
+	if l.injectFault(w, r) {
+		return
+	}
+
+	if l.cfg.RespDelay > 0*time.Second {
+		l.logger.Info(fmt.Sprintf("waiting %s before reading/responding...", l.cfg.RespDelay), "delay", l.cfg.RespDelay)
+		time.Sleep(l.cfg.RespDelay)
+	}
+
+	// the body is streamed through each of these writers so it is never held in memory
+	counter := &countingWriter{}
+	defer func() { l.bytes.Add(counter.n) }()
+	writers := []io.Writer{counter}
+	var (
+		dump   *prefixBuffer
+		saved  *os.File
+		hasher hash.Hash
+	)
+
+	if l.cfg.Verbose && l.cfg.MaxDumpBytes > 0 {
+		dump = &prefixBuffer{limit: l.cfg.MaxDumpBytes}
+		writers = append(writers, dump)
+	}
+
+	if l.cfg.SaveDir != "" {
+		f, err := createBodyFile(l.cfg.SaveDir)
+		if err != nil {
+			l.logger.Error(fmt.Sprintf("error saving body: %v", err), "status", http.StatusInternalServerError, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			if err := f.Close(); err != nil {
+				l.logger.Error(fmt.Sprintf("error closing %v: %v", f.Name(), err), "path", f.Name(), "error", err)
+			}
+		}()
+
+		saved = f
+		writers = append(writers, f)
+	}
+
+	if l.cfg.Hash {
+		hasher = sha256.New()
+		writers = append(writers, hasher)
+	}
+
+	if expect {
+		l.logger.Info(fmt.Sprintf("sending 100 Continue for request with content length %v", r.ContentLength), "content_length", r.ContentLength, "continued", true)
+		w.WriteHeader(http.StatusContinue)
+	}
+
+	var body io.Reader = r.Body
+	if l.cfg.ReadRate > 0 {
+		body = newThrottledReader(body, l.cfg.ReadRate)
+	}
+
+	// gzip bodies are decompressed before being counted, echoed, or saved
+	var compressed *countingReader
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		compressed = &countingReader{r: body}
+		zr, err := gzip.NewReader(compressed)
+		if err != nil {
+			l.logger.Warn(fmt.Sprintf("rejecting request with malformed gzip body: %v", err), "status", http.StatusBadRequest, "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		defer zr.Close()
+		body = zr
+		if l.cfg.MaxBodyBytes > 0 {
+			// the limit on r.Body only counts compressed bytes, which can expand to far more
+			body = http.MaxBytesReader(w, io.NopCloser(zr), l.cfg.MaxBodyBytes)
+		}
+	}
+
+	status := l.statuses.next()
+	if status != http.StatusOK {
+		l.logger.Info(fmt.Sprintf("responding with status %v", status), "status", status)
+	}
+
+	echo := l.cfg.Echo && bodyAllowedForStatus(status)
+	if l.cfg.Echo && !echo {
+		l.logger.Info(fmt.Sprintf("not echoing body, responses with status %v have no body", status), "status", status)
+	}
+
+	if echo {
+		// echoing writes the response while the body is still being read
+		if err := http.NewResponseController(w).EnableFullDuplex(); err != nil {
+			l.logger.Error(fmt.Sprintf("error enabling full duplex for echo: %v", err), "error", err)
+		}
+
+		// the hash isn't known until the body has been echoed, so it is sent as a trailer
+		if hasher != nil {
+			w.Header().Set("Trailer", bodyHashHeader)
+		} else if r.ContentLength >= 0 && compressed == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+		}
+
+		w.WriteHeader(status)
+		writers = append(writers, w)
+	}
+
+	readStart := time.Now()
+	if _, err := l.copyBody(io.MultiWriter(writers...), body); err != nil {
+		errStatus := http.StatusInternalServerError
+		var (
+			maxBytesErr *http.MaxBytesError
+			pathErr     *fs.PathError
+		)
+
+		switch {
+		case errors.As(err, &maxBytesErr):
+			l.logger.Warn(fmt.Sprintf("rejecting request after reading more than max-body-bytes of %v", maxBytesErr.Limit), "status", http.StatusRequestEntityTooLarge, "max_body_bytes", maxBytesErr.Limit)
+			errStatus = http.StatusRequestEntityTooLarge
+		case compressed != nil && (isGzipError(err) || errors.Is(err, io.ErrUnexpectedEOF)):
+			l.logger.Warn(fmt.Sprintf("rejecting request with malformed gzip body: %v", err), "status", http.StatusBadRequest, "error", err)
+			errStatus = http.StatusBadRequest
+		case errors.As(err, &pathErr):
+			l.logger.Error(fmt.Sprintf("error saving body: %v", err), "error", err)
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			l.logger.Warn(fmt.Sprintf("timed out streaming body after %v bytes, the connection will be closed: %v", counter.n, err), "size", counter.n, "read_timeout", l.cfg.ReadTimeout, "write_timeout", l.cfg.WriteTimeout, "error", err)
+		default:
+			l.logger.Error(fmt.Sprintf("error streaming body: %v", err), "error", err)
+		}
+
+		// the status has already been sent when echoing
+		if !echo {
+			w.WriteHeader(errStatus)
+		}
+
+		return
+	}
+
+	if l.cfg.ReadRate > 0 {
+		readDuration := time.Since(readStart)
+		l.logger.Info(fmt.Sprintf("read %v bytes from body in %s at a read-rate of %v bytes/s", counter.n, readDuration, l.cfg.ReadRate), "size", counter.n, "duration", readDuration, "read_rate", l.cfg.ReadRate)
+	} else {
+		l.logger.Info(fmt.Sprintf("read %v bytes from body", counter.n), "size", counter.n)
+	}
+
+	if compressed != nil {
+		l.logger.Info(fmt.Sprintf("decompressed %v gzip bytes (content length %v) to %v bytes", compressed.n, r.ContentLength, counter.n), "compressed_size", compressed.n, "content_length", r.ContentLength, "size", counter.n)
+	}
+
+	if dump != nil {
+		l.logger.Info(fmt.Sprintf("body (%v of %v bytes):\n%s", len(dump.buf), counter.n, dump.buf), "size", counter.n)
+	}
+
+	if saved != nil {
+		l.logger.Info(fmt.Sprintf("saved body to %v", saved.Name()), "path", saved.Name())
+	}
+
+	if hasher != nil {
+		sum := hex.EncodeToString(hasher.Sum(nil))
+		l.logger.Info(fmt.Sprintf("body sha256 %v", sum), "sha256", sum)
+		w.Header().Set(bodyHashHeader, sum)
+	}
+
+	switch {
+	case l.cfg.Reflect:
+		l.writeReflection(w, r, status, counter.n)
+	case l.cfg.RespSize > 0:
+		l.writeGenerated(w, r, status)
+	case !echo:
+		w.WriteHeader(status)
+	}
+}
+
+// checkWriteTimeout logs when handling a request that started at start outlasted the write timeout, in which case
+// http.Server closes the connection without the client receiving the response
+func (l *listener) checkWriteTimeout(start time.Time) {
+	if l.cfg.WriteTimeout <= 0 {
+		return
+	}
+
+	if elapsed := time.Since(start); elapsed > l.cfg.WriteTimeout {
+		l.logger.Warn(fmt.Sprintf("handling the request took %s, exceeding write-timeout of %s, the response will not reach the client", elapsed, l.cfg.WriteTimeout), "duration", elapsed, "write_timeout", l.cfg.WriteTimeout)
+	}
+}
+
+// writeGenerated streams RespSize bytes of generated data as the response body with status, for clients downloading a large response.
+// HEAD requests only get the headers
+func (l *listener) writeGenerated(w http.ResponseWriter, r *http.Request, status int) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(l.cfg.RespSize, 10))
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	start := time.Now()
+	n, err := io.Copy(w, io.LimitReader(&repeatingReader{}, l.cfg.RespSize))
+	if err != nil {
+		l.logger.Error(fmt.Sprintf("error writing response after %v of %v bytes: %v", n, l.cfg.RespSize, err), "size", n, "resp_size", l.cfg.RespSize, "error", err)
+		return
+	}
+
+	duration := time.Since(start)
+	l.logger.Info(fmt.Sprintf("wrote %v byte response in %s", n, duration), "size", n, "duration", duration)
+}
+
+// defaultReadBuffer is the size of the buffer io.Copy reads through, used when ListenConfig.ReadBuffer isn't set
+const defaultReadBuffer = 32 << 10
+
+// copyBody copies src to dst through a buffer of ReadBuffer bytes. src and dst are wrapped so io.CopyBuffer can't bypass the
+// buffer with io.WriterTo or io.ReaderFrom, such as a TCP connection splicing straight to io.Discard
+func (l *listener) copyBody(dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, l.cfg.ReadBuffer)
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, buf)
+}
+
+// isGzipError reports whether err was caused by a malformed gzip stream
+func isGzipError(err error) bool {
+	var corruptErr flate.CorruptInputError
+	return errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) || errors.As(err, &corruptErr)
+}
+
+// statusRotation cycles through a set of response status codes, one per request
+type statusRotation struct {
+	statuses []int
+	count    atomic.Uint64
+}
+
+func (s *statusRotation) next() int {
+	i := s.count.Add(1) - 1
+	return s.statuses[i%uint64(len(s.statuses))]
+}
+
+// bodyAllowedForStatus reports whether a response with status may have a body, which 1xx, 204, and 304 responses can't
+func bodyAllowedForStatus(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// ParseStatuses parses a comma separated list of status codes, such as 200,200,503
+func ParseStatuses(str string) ([]int, error) {
+	parts := strings.Split(str, ",")
+	statuses := make([]int, 0, len(parts))
+	for _, part := range parts {
+		status, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || status < 100 || status > 999 {
+			return nil, fmt.Errorf("invalid status %q, must be a 3 digit status code", part)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// createBodyFile creates a new file in dir to save a request body to, named with the current time and a short random suffix
+func createBodyFile(dir string) (*os.File, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate file name: %w", err)
+	}
+
+	name := fmt.Sprintf("%v-%v.body", time.Now().UTC().Format("20060102T150405.000000000Z"), hex.EncodeToString(suffix))
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create body file: %w", err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,161 @@
+package reqtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startListener runs Listen with cfg on a free address until the test ends, returning the address once it accepts requests
+func startListener(t *testing.T, cfg ListenConfig) string {
+	t.Helper()
+	cfg.Address = freeAddress(t)
+	cfg.ShutdownTimeout = time.Second
+	cfg.Logger = discardLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- Listen(ctx, cfg) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Error(err)
+		}
+	})
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if conn, err := net.DialTimeout("tcp", cfg.Address, time.Second); err == nil {
+			conn.Close()
+			return cfg.Address
+		}
+	}
+
+	t.Fatal("listener did not start")
+	return ""
+}
+
+func TestListenMaxBodyBytesGzip(t *testing.T) {
+	const limit = 1024
+	address := startListener(t, ListenConfig{MaxBodyBytes: limit})
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{name: "within the limit once decompressed", size: limit, want: http.StatusOK},
+		{name: "over the limit once decompressed", size: 100 * limit, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			zw := gzip.NewWriter(&body)
+			zw.Write([]byte(strings.Repeat("x", tt.size)))
+			zw.Close()
+			if body.Len() > limit {
+				t.Fatalf("compressed body of %v bytes is over the limit, the test needs a more compressible body", body.Len())
+			}
+
+			req, err := http.NewRequest(http.MethodPut, "http://"+address, &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Encoding", "gzip")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("got status %v, want %v", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+// countingZeros reads n zeros, counting the bytes read
+type countingZeros struct {
+	n    int64
+	read atomic.Int64
+}
+
+func (c *countingZeros) Read(p []byte) (int, error) {
+	remaining := c.n - c.read.Load()
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	p = p[:min(int64(len(p)), remaining)]
+	clear(p)
+	c.read.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func TestListenDropRateMaxBodyBytes(t *testing.T) {
+	const size = 256 << 20
+	address := startListener(t, ListenConfig{DropRate: 1, MaxBodyBytes: 1024})
+	body := &countingZeros{n: size}
+	// the body has no content length, so it is only limited once read
+	req, err := http.NewRequest(http.MethodPut, "http://"+address, io.NopCloser(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// socket buffers take some of the body beyond the limit, but nowhere near all of it
+	if read := body.read.Load(); read >= size {
+		t.Errorf("listener read all %v bytes of the body before dropping the connection", read)
+	}
+}
+
+func TestListenEchoStatusWithoutBody(t *testing.T) {
+	address := startListener(t, ListenConfig{Echo: true, Hash: true, Statuses: []int{http.StatusNoContent, http.StatusOK}})
+	body := bytes.Repeat([]byte("x"), 256<<10)
+	sum := sha256.Sum256(body)
+	want := hex.EncodeToString(sum[:])
+	for _, status := range []int{http.StatusNoContent, http.StatusOK} {
+		resp, err := http.Post("http://"+address, "application/octet-stream", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		echoed, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != status {
+			t.Fatalf("got status %v, want %v", resp.StatusCode, status)
+		}
+
+		wantEchoed := len(body)
+		if status == http.StatusNoContent {
+			wantEchoed = 0
+		}
+
+		if len(echoed) != wantEchoed {
+			t.Errorf("status %v echoed %v bytes, want %v", status, len(echoed), wantEchoed)
+		}
+
+		// the hash is only sent once the whole body has been read, as a header without an echo or a trailer with one
+		got := resp.Header.Get(bodyHashHeader) + resp.Trailer.Get(bodyHashHeader)
+		if got != want {
+			t.Errorf("status %v got body hash %q, want %q", status, got, want)
+		}
+	}
+}
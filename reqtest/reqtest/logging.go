@@ -0,0 +1,85 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LevelSummary is the level end of run summaries are logged at, above every standard level so summaries are always logged
+const LevelSummary = slog.Level(12)
+
+// NewLogger creates a logger writing messages at level or above to w in the given format. The text format matches the standard
+// logger's output, the json format writes one object per line with time, level, msg and any fields of the message
+func NewLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	switch format {
+	case LogFormatText, "":
+		return slog.New(&textHandler{w: w, mu: &sync.Mutex{}, level: level}), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: replaceSummaryLevel})), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %v, must be one of: %v, %v", format, LogFormatText, LogFormatJSON)
+	}
+}
+
+// defaultLogger is used when a config doesn't provide a logger
+func defaultLogger() *slog.Logger {
+	return slog.New(&textHandler{w: os.Stderr, mu: &sync.Mutex{}, level: slog.LevelInfo})
+}
+
+// replaceSummaryLevel names LevelSummary SUMMARY rather than slog's default of ERROR+4
+func replaceSummaryLevel(_ []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && a.Value.Any() == LevelSummary {
+		return slog.String(slog.LevelKey, "SUMMARY")
+	}
+
+	return a
+}
+
+// textHandler writes each record's message prefixed with the date and time, like the standard logger.
+// Messages already include the values of their fields so attributes are not written, except those added with
+// Logger.With, such as the target of SendTargets, whose values lead each message in brackets, like "[target] msg"
+type textHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	level  slog.Level
+	prefix string
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	line := r.Time.Format("2006/01/02 15:04:05") + " " + h.prefix + strings.TrimSuffix(r.Message, "\n") + "\n"
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line)
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	with := *h
+	for _, a := range attrs {
+		with.prefix += "[" + a.Value.String() + "] "
+	}
+
+	return &with
+}
+
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
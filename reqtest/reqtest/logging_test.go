@@ -0,0 +1,31 @@
+package reqtest
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestTextHandlerWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, LogFormatText, slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.With("target", "http://a").Info("sent 2 bytes", "size", 2)
+	logger.Info("summary")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %v lines, want 2: %q", len(lines), buf.String())
+	}
+
+	if !strings.HasSuffix(lines[0], " [http://a] sent 2 bytes") {
+		t.Errorf("got %q, want the target to prefix the message", lines[0])
+	}
+
+	if !strings.HasSuffix(lines[1], " summary") || strings.Contains(lines[1], "[http://a]") {
+		t.Errorf("got %q, want the parent logger unaffected by With", lines[1])
+	}
+}
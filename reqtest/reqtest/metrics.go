@@ -0,0 +1,79 @@
+package reqtest
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// listenerMetrics are the Prometheus metrics of requests handled by the listener, registered on their own registry
+// so only the listener's metrics are served
+type listenerMetrics struct {
+	registry  *prometheus.Registry
+	requests  prometheus.Counter
+	bodyBytes prometheus.Counter
+	responses *prometheus.CounterVec
+	inFlight  prometheus.Gauge
+}
+
+func newListenerMetrics() *listenerMetrics {
+	m := &listenerMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "reqtest_requests_total",
+			Help: "Total number of requests received.",
+		}),
+		bodyBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "reqtest_request_body_bytes_total",
+			Help: "Total number of request body bytes read.",
+		}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "reqtest_responses_total",
+			Help: "Total number of responses by status code.",
+		}, []string{"status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "reqtest_requests_in_flight",
+			Help: "Number of requests currently being handled.",
+		}),
+	}
+
+	m.registry.MustRegister(m.requests, m.bodyBytes, m.responses, m.inFlight)
+	return m
+}
+
+// handler serves the metrics in the Prometheus exposition format
+func (m *listenerMetrics) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// instrument wraps next so each request it handles is counted, along with its body bytes and response status
+func (m *listenerMetrics) instrument(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.requests.Inc()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		body := &meteredBody{ReadCloser: r.Body, bytes: m.bodyBytes}
+		r.Body = body
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rw, r)
+		m.responses.WithLabelValues(strconv.Itoa(rw.status)).Inc()
+	}
+}
+
+// meteredBody adds the bytes read from a request body to a counter as they are read
+type meteredBody struct {
+	io.ReadCloser
+	bytes prometheus.Counter
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes.Add(float64(n))
+	return n, err
+}
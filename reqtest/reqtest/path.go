@@ -0,0 +1,26 @@
+package reqtest
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// withPath returns address with path joined onto its path, so a slash between them is neither doubled nor missing
+func withPath(address, path string) (string, error) {
+	if strings.ContainsAny(path, "?#") {
+		return "", fmt.Errorf("invalid path %q, cannot contain a query or fragment", path)
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %w", err)
+	}
+
+	joined := u.JoinPath(path).String()
+	if _, err := url.Parse(joined); err != nil {
+		return "", fmt.Errorf("invalid path %q: %w", path, err)
+	}
+
+	return joined, nil
+}
@@ -0,0 +1,154 @@
+package reqtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"mime/multipart"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// payloadGenerator produces a request body for the given payload size
+type payloadGenerator func(size int) ([]byte, error)
+
+const (
+	PatternRandom    = "random"
+	PatternZeros     = "zeros"
+	PatternRepeating = "repeating"
+)
+
+// payloadPatterns builds the generator for each payload pattern. Random patterns read from src, and raw disables hex encoding.
+var payloadPatterns = map[string]func(src io.Reader, raw bool) payloadGenerator{
+	PatternRandom: func(src io.Reader, raw bool) payloadGenerator {
+		if raw {
+			return rawPayload(src)
+		}
+
+		return hexPayload(src)
+	},
+	PatternZeros: func(io.Reader, bool) payloadGenerator {
+		return zerosPayload
+	},
+	PatternRepeating: func(io.Reader, bool) payloadGenerator {
+		return repeatingPayload
+	},
+}
+
+// newPayloadGenerator returns the generator for the named pattern
+func newPayloadGenerator(pattern string, src io.Reader, raw bool) (payloadGenerator, error) {
+	newGenerator, ok := payloadPatterns[pattern]
+	if !ok {
+		names := make([]string, 0, len(payloadPatterns))
+		for name := range payloadPatterns {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+		return nil, fmt.Errorf("invalid pattern %v, must be one of: %v", pattern, strings.Join(names, ", "))
+	}
+
+	return newGenerator(src, raw), nil
+}
+
+func zerosPayload(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+// repeatingBlock is the ASCII block repeated by the repeating pattern
+const repeatingBlock = "reqtest repeating payload 0123456789 abcdefghijklmnopqrstuvwxyz\n"
+
+func repeatingPayload(size int) ([]byte, error) {
+	b := bytes.Repeat([]byte(repeatingBlock), size/len(repeatingBlock)+1)
+	return b[:size], nil
+}
+
+// hexPayload produces hex encoded bytes read from src. Each source byte encodes to two hex characters,
+// so enough bytes are read to cover odd sizes and the encoding is trimmed to exactly size bytes.
+func hexPayload(src io.Reader) payloadGenerator {
+	return func(size int) ([]byte, error) {
+		b := make([]byte, (size+1)/2)
+		if _, err := io.ReadFull(src, b); err != nil {
+			return nil, fmt.Errorf("failed to generate bytes: %w", err)
+		}
+
+		encoded := make([]byte, hex.EncodedLen(len(b)))
+		hex.Encode(encoded, b)
+		return encoded[:size], nil
+	}
+}
+
+// rawPayload produces bytes read directly from src
+func rawPayload(src io.Reader) payloadGenerator {
+	return func(size int) ([]byte, error) {
+		b := make([]byte, size)
+		if _, err := io.ReadFull(src, b); err != nil {
+			return nil, fmt.Errorf("failed to generate bytes: %w", err)
+		}
+
+		return b, nil
+	}
+}
+
+// seededReader produces deterministic bytes from a seeded math/rand source.
+// It is safe for concurrent use, and is meant for reproducible payloads rather than anything security sensitive.
+type seededReader struct {
+	mu  sync.Mutex
+	rng *mrand.Rand
+}
+
+func newSeededReader(seed int64) *seededReader {
+	return &seededReader{rng: mrand.New(mrand.NewSource(seed))}
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Read(p)
+}
+
+// fixedPayload always produces the same body, regardless of the requested size
+func fixedPayload(body []byte) payloadGenerator {
+	return func(int) ([]byte, error) {
+		return body, nil
+	}
+}
+
+// gzipPayload compresses body with gzip
+func gzipPayload(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// multipartPayload wraps body as a file in a multipart/form-data body under fieldName, returning the multipart body and its Content-Type
+func multipartPayload(fieldName string, body []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile(fieldName, "payload")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create multipart payload: %w", err)
+	}
+
+	if _, err := part.Write(body); err != nil {
+		return nil, "", fmt.Errorf("failed to create multipart payload: %w", err)
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to create multipart payload: %w", err)
+	}
+
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
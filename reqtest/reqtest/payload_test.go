@@ -0,0 +1,122 @@
+package reqtest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// testPayloadSizes covers empty and single byte payloads, odd and even sizes around the repeating block length, and sizes
+// larger than the buffers the stream generators are read through
+var testPayloadSizes = []int{0, 1, 2, 3, 63, 64, 65, 127, 4095, 4096, 32<<10 + 1, 1<<20 + 1}
+
+func TestPayloadGenerators(t *testing.T) {
+	tests := []struct {
+		pattern string
+		raw     bool
+		isHex   bool
+	}{
+		{pattern: PatternRandom, isHex: true},
+		{pattern: PatternRandom, raw: true},
+		{pattern: PatternZeros},
+		{pattern: PatternRepeating},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v/raw=%v", tt.pattern, tt.raw), func(t *testing.T) {
+			generate, err := newPayloadGenerator(tt.pattern, newSeededReader(1), tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stream, err := newStreamGenerator(tt.pattern, newSeededReader(1), tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, size := range testPayloadSizes {
+				body, err := generate(size)
+				if err != nil {
+					t.Fatalf("size %v: %v", size, err)
+				}
+
+				if len(body) != size {
+					t.Fatalf("size %v: generated %v bytes", size, len(body))
+				}
+
+				if tt.isHex {
+					if _, err := hex.DecodeString(string(body[:size/2*2])); err != nil {
+						t.Fatalf("size %v: body is not hex encoded: %v", size, err)
+					}
+				}
+
+				streamed, err := io.ReadAll(stream(size))
+				if err != nil {
+					t.Fatalf("size %v: streaming: %v", size, err)
+				}
+
+				if len(streamed) != size {
+					t.Fatalf("size %v: streamed %v bytes", size, len(streamed))
+				}
+
+				// both generators read the same seeded sequence, so they produce the same bytes however the stream is read
+				if !bytes.Equal(streamed, body) {
+					t.Fatalf("size %v: streamed payload differs from the generated payload", size)
+				}
+			}
+		})
+	}
+}
+
+func TestHexPayloadOddSizes(t *testing.T) {
+	for _, size := range testPayloadSizes {
+		body, err := hexPayload(newSeededReader(1))(size)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body) != size {
+			t.Errorf("hexPayload(%v) generated %v bytes", size, len(body))
+		}
+	}
+}
+
+func TestHexReaderSmallReads(t *testing.T) {
+	// single byte reads leave the second character of each encoded byte pending for the next read
+	const size = 101
+	r := io.LimitReader(&hexReader{src: newSeededReader(1)}, size)
+	var got []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := hexPayload(newSeededReader(1))(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read %q one byte at a time, want %q", got, want)
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	if _, err := newPayloadGenerator("bogus", newSeededReader(1), false); err == nil {
+		t.Error("newPayloadGenerator accepted an invalid pattern")
+	}
+
+	if _, err := newStreamGenerator("bogus", newSeededReader(1), false); err == nil {
+		t.Error("newStreamGenerator accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,108 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// logPlan logs the requests a run with cfg would make, always logged at LevelSummary, without sending any of them
+func (s *sender) logPlan(ctx context.Context, cfg SendConfig, sizes []int) {
+	logf := func(msg string, args ...any) { s.logger.Log(ctx, LevelSummary, msg, args...) }
+	// the address of a unix socket is replaced by a placeholder host, so the socket is shown instead
+	target := s.address
+	if _, ok := unixSocketPath(cfg.Address); ok {
+		target = cfg.Address
+	}
+
+	logf(fmt.Sprintf("dry run, would send %v requests to %v", s.method, target), "method", s.method, "address", target, "raw_tcp", cfg.RawTCP)
+	for _, line := range s.planHeaders() {
+		logf("header "+line, "header", line)
+	}
+
+	if !cfg.RawTCP {
+		logf(fmt.Sprintf("expecting a %v response", formatStatusCodes(s.okStatus)), "expect_status", s.okStatus)
+	}
+
+	concurrency := max(cfg.Concurrency, 1)
+	switch {
+	case len(cfg.Replay) > 0:
+		for i, req := range cfg.Replay {
+			method := strings.ToUpper(req.Method)
+			if method == "" {
+				method = s.method
+			}
+
+			logf(fmt.Sprintf("replay %v of %v: %v %v with %v bytes", i+1, len(cfg.Replay), method, req.URL, max(req.Size, len(req.Body))), "method", method, "url", req.URL, "size", max(req.Size, len(req.Body)))
+		}
+	case cfg.Duration > 0:
+		logf(fmt.Sprintf("would send %v bytes repeatedly for %s across %v workers", sizes[0], cfg.Duration, concurrency), "size", sizes[0], "duration", cfg.Duration, "concurrency", concurrency)
+	default:
+		repeat := max(cfg.Repeat, 1)
+		var total int64
+		formatted := make([]string, len(sizes))
+		for i, size := range sizes {
+			total += int64(size) * int64(repeat)
+			formatted[i] = fmt.Sprint(size)
+		}
+
+		logf(fmt.Sprintf("sizes: %v", strings.Join(formatted, ", ")), "sizes", sizes)
+		logf(fmt.Sprintf("would send %v sizes %v times each across %v workers, %v requests totaling %v bytes", len(sizes), repeat, concurrency, len(sizes)*repeat, total),
+			"sizes", len(sizes), "repeat", repeat, "concurrency", concurrency, "requests", len(sizes)*repeat, "total_bytes", total)
+		if iterations := cfg.Iterations; iterations > 1 {
+			logf(fmt.Sprintf("would run the ramp %v times, %v requests totaling %v bytes in all", iterations, len(sizes)*repeat*iterations, total*int64(iterations)),
+				"iterations", iterations, "requests", len(sizes)*repeat*iterations, "total_bytes", total*int64(iterations))
+		}
+	}
+
+	if cfg.Warmup > 0 {
+		logf(fmt.Sprintf("would first send %v warm-up requests of %v bytes", cfg.Warmup, sizes[0]), "warmup", cfg.Warmup, "size", sizes[0])
+	}
+}
+
+// planHeaders returns the headers every request would be sent with as sorted "Key: Value" lines, with credentials redacted
+func (s *sender) planHeaders() []string {
+	header := s.header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", s.userAgent)
+	}
+
+	switch {
+	case s.multipart != "":
+		header.Set("Content-Type", "multipart/form-data")
+	case s.contentType != "":
+		header.Set("Content-Type", s.contentType)
+	}
+
+	if s.bearerToken != "" || s.basicAuth != "" {
+		header.Set("Authorization", "[redacted]")
+	}
+
+	if s.gzip {
+		header.Set("Content-Encoding", "gzip")
+	}
+
+	if s.expect {
+		header.Set("Expect", "100-continue")
+	}
+
+	if s.acceptGzip && header.Get("Accept-Encoding") == "" {
+		header.Set("Accept-Encoding", "gzip")
+	}
+
+	var lines []string
+	for key, values := range header {
+		for _, value := range values {
+			lines = append(lines, key+": "+value)
+		}
+	}
+
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,50 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// progress counts requests as they complete so running totals can be read while workers are still sending
+type progress struct {
+	completed atomic.Int64
+	failed    atomic.Int64
+	bytes     atomic.Int64
+}
+
+func (p *progress) record(size int, err error) {
+	p.completed.Add(1)
+	if err != nil {
+		p.failed.Add(1)
+		return
+	}
+
+	p.bytes.Add(int64(size))
+}
+
+// logProgress logs running totals every interval until ctx is done. Throughput is measured over the last interval
+func (s *sender) logProgress(ctx context.Context, interval time.Duration) {
+	start := time.Now()
+	last := start
+	var lastCompleted, lastBytes int64
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			completed, failed, bytes := s.progress.completed.Load(), s.progress.failed.Load(), s.progress.bytes.Load()
+			since := now.Sub(last).Seconds()
+			requestsPerSec := float64(completed-lastCompleted) / since
+			bytesPerSec := float64(bytes-lastBytes) / since
+			elapsed := now.Sub(start).Round(time.Millisecond)
+			inUse, idle := s.conns.current()
+			s.logger.Info(fmt.Sprintf("progress: %v requests completed, %v failed, %.2f requests/s, %.2f bytes/s, %v connections in use, %v idle, %s elapsed", completed, failed, requestsPerSec, bytesPerSec, inUse, idle, elapsed),
+				"completed", completed, "failed", failed, "requests_per_sec", requestsPerSec, "bytes_per_sec", bytesPerSec, "conns_in_use", inUse, "conns_idle", idle, "elapsed", elapsed)
+			last, lastCompleted, lastBytes = now, completed, bytes
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package reqtest
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContext injects W3C traceparent and tracestate headers
+var traceContext = propagation.TraceContext{}
+
+// newSpanContext returns a sampled span context with a random trace and span ID, so each request starts its own trace
+func newSpanContext() trace.SpanContext {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	rand.Read(traceID[:])
+	rand.Read(spanID[:])
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+// injectTraceContext sets the traceparent and tracestate headers of req for a new trace, returning its span context
+func injectTraceContext(req *http.Request) trace.SpanContext {
+	sc := newSpanContext()
+	traceContext.Inject(trace.ContextWithSpanContext(context.Background(), sc), propagation.HeaderCarrier(req.Header))
+	return sc
+}
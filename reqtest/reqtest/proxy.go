@@ -0,0 +1,49 @@
+package reqtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// parseProxy parses a proxy URL, which must use the http, https, or socks5 scheme
+func parseProxy(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy scheme %q, must be one of: http, https, socks5", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %v, missing host", proxyURL.Redacted())
+	}
+
+	return proxyURL, nil
+}
+
+// proxyTransport creates a transport that sends every request through proxyURL rather than any proxy from the environment
+func proxyTransport(proxyURL *url.URL) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport
+}
+
+// environmentProxy returns the proxy the environment configures for requests to address, if any
+func environmentProxy(address string) *url.URL {
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil
+	}
+
+	proxyURL, err := http.ProxyFromEnvironment(req)
+	if err != nil {
+		return nil
+	}
+
+	return proxyURL
+}
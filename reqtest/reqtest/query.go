@@ -0,0 +1,32 @@
+package reqtest
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseQueryParam parses a query parameter in the form "key=value". The value is taken as is and encoded when sent
+func ParseQueryParam(str string) (string, string, error) {
+	key, value, ok := strings.Cut(str, "=")
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid query parameter %q, must be in the form \"key=value\"", str)
+	}
+
+	return key, value, nil
+}
+
+// withQuery returns address with query encoded and appended after any query it already has
+func withQuery(address string, query url.Values) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %w", err)
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+
+	u.RawQuery += query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,266 @@
+package reqtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rawTCPPrefix may optionally prefix a raw-tcp send address, which is otherwise a plain host:port
+const rawTCPPrefix = "tcp://"
+
+// rawTCPAddress returns the host:port of a raw-tcp send address
+func rawTCPAddress(address string) (string, error) {
+	address = strings.TrimPrefix(address, rawTCPPrefix)
+	if strings.Contains(address, "://") {
+		return "", fmt.Errorf("invalid raw-tcp address %v, must be host:port", address)
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", fmt.Errorf("invalid raw-tcp address, must be host:port: %w", err)
+	}
+
+	return address, nil
+}
+
+// listenRaw accepts plain connections rather than serving HTTP, counting the bytes read from each until the client closes its
+// side and writing them back when echoing. Once ctx is done or MaxRequests connections have been accepted it stops accepting
+// and waits up to ShutdownTimeout for open connections, whose reads are ended once ctx is done
+func (l *listener) listenRaw(ctx context.Context) error {
+	network, address := listenNetwork(l.cfg.Network, l.cfg.Address)
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("could not listen: %w", err)
+	}
+
+	l.logger.Info(fmt.Sprintf("listening for raw tcp connections on %v", l.cfg.Address), "address", l.cfg.Address, "raw_tcp", true)
+	var open rawConns
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+		if n := open.expire(); n > 0 {
+			l.logger.Info(fmt.Sprintf("ending reads of %v open connections", n), "connections", n)
+		}
+	})
+	defer stop()
+	defer ln.Close()
+
+	var (
+		wg    sync.WaitGroup
+		conns atomic.Int64
+		total atomic.Int64
+	)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil && ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not accept connection: %w", err)
+		}
+
+		open.add(conn)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer open.remove(conn)
+			conns.Add(1)
+			total.Add(l.handleRaw(ctx, conn))
+		}()
+
+		if l.cfg.MaxRequests > 0 && l.served.Add(1) >= l.cfg.MaxRequests {
+			l.logger.Info(fmt.Sprintf("accepted max-requests of %v connections", l.cfg.MaxRequests), "max_requests", l.cfg.MaxRequests)
+			break
+		}
+	}
+
+	l.logger.Info(fmt.Sprintf("shutting down, waiting up to %s for open connections...", l.cfg.ShutdownTimeout), "timeout", l.cfg.ShutdownTimeout)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(l.cfg.ShutdownTimeout):
+		return fmt.Errorf("failed to shut down cleanly: %w", context.DeadlineExceeded)
+	}
+
+	l.logger.Log(ctx, LevelSummary, fmt.Sprintf("read %v bytes across %v connections", total.Load(), conns.Load()), "size", total.Load(), "connections", conns.Load())
+	l.logger.Info("shut down cleanly")
+	return nil
+}
+
+// handleRaw reads conn until the client closes its side or ctx is done, echoing what it reads when configured, and returns
+// the bytes read
+func (l *listener) handleRaw(ctx context.Context, conn net.Conn) int64 {
+	defer conn.Close()
+	remote := conn.RemoteAddr().String()
+	l.logger.Info(fmt.Sprintf("accepted connection from %v", remote), "remote_addr", remote)
+	start := time.Now()
+	var w io.Writer = io.Discard
+	if l.cfg.Echo {
+		w = conn
+	}
+
+	var r io.Reader = conn
+	if l.cfg.ReadRate > 0 {
+		r = newThrottledReader(conn, l.cfg.ReadRate)
+	}
+
+	n, err := l.copyBody(w, r)
+	if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+		l.logger.Info(fmt.Sprintf("closed connection from %v at shutdown after %v bytes", remote, n), "remote_addr", remote, "size", n)
+		return n
+	}
+
+	if err != nil {
+		l.logger.Error(fmt.Sprintf("connection from %v failed after %v bytes: %v", remote, n, err), "remote_addr", remote, "size", n, "error", err)
+		return n
+	}
+
+	duration := time.Since(start)
+	l.logger.Info(fmt.Sprintf("read %v bytes from connection from %v in %s", n, remote, duration), "remote_addr", remote, "size", n, "duration", duration)
+	return n
+}
+
+// rawConns tracks open raw connections so their reads can be ended at shutdown, much as http.Server.Shutdown closes idle
+// connections
+type rawConns struct {
+	mu      sync.Mutex
+	conns   map[net.Conn]struct{}
+	closing bool
+}
+
+// add tracks conn, ending its reads straight away if connections have already been expired
+func (r *rawConns) add(conn net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closing {
+		conn.SetReadDeadline(time.Now())
+		return
+	}
+
+	if r.conns == nil {
+		r.conns = make(map[net.Conn]struct{})
+	}
+
+	r.conns[conn] = struct{}{}
+}
+
+func (r *rawConns) remove(conn net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, conn)
+}
+
+// expire ends the reads of every open connection, and of any added later, returning how many were open
+func (r *rawConns) expire() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closing = true
+	for conn := range r.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+
+	return len(r.conns)
+}
+
+// attemptRaw writes body over a new connection, then half closes it and reads the response until EOF or the configured
+// number of response bytes. The response is read while writing so a peer echoing the payload can't stall the write
+func (s *sender) attemptRaw(ctx context.Context, body []byte) (Result, error) {
+	result := Result{Size: len(body)}
+	if s.client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.client.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return result, fmt.Errorf("could not connect: %w", err)
+	}
+
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	type response struct {
+		n   int64
+		err error
+	}
+
+	h := sha256.New()
+	read := make(chan response, 1)
+	go func() {
+		var w io.Writer = io.Discard
+		if s.verify {
+			w = h
+		}
+
+		var r io.Reader = conn
+		if s.respBytes > 0 {
+			r = io.LimitReader(conn, s.respBytes)
+		}
+
+		n, err := io.Copy(w, r)
+		read <- response{n: n, err: err}
+	}()
+
+	var payload io.Reader = bytes.NewReader(body)
+	if s.bandwidth > 0 {
+		payload = newThrottledReader(payload, s.bandwidth)
+	}
+
+	if _, err := io.Copy(conn, payload); err != nil {
+		return result, rawTCPError("could not write payload", time.Since(start), err)
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	}
+
+	resp := <-read
+	result.Duration = time.Since(start)
+	if resp.err != nil {
+		return result, rawTCPError("could not read response", result.Duration, resp.err)
+	}
+
+	s.logger.Info(fmt.Sprintf("request of %v bytes read %v response bytes", len(body), resp.n), "size", len(body), "response_size", resp.n)
+	if s.verify {
+		sentSum := sha256.Sum256(body)
+		if receivedSum := h.Sum(nil); !bytes.Equal(receivedSum, sentSum[:]) {
+			result.Mismatch = true
+			return result, fmt.Errorf("%w: sent sha256 %x, received %x", ErrIntegrityMismatch, sentSum, receivedSum)
+		}
+	}
+
+	return result, nil
+}
+
+// rawTCPError wraps a connection error, reporting deadline errors as the request timing out
+func rawTCPError(action string, elapsed time.Duration, err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("request timed out after %s: %w", elapsed.Round(time.Millisecond), err)
+	}
+
+	return fmt.Errorf("%v: %w", action, err)
+}
@@ -0,0 +1,54 @@
+package reqtest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListenRawShutsDownWithIdleConnections(t *testing.T) {
+	address := freeAddress(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Listen(ctx, ListenConfig{Address: address, RawTCP: true, ShutdownTimeout: 5 * time.Second, Logger: discardLogger()})
+	}()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if conn, err = net.Dial("tcp", address); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the client stays connected without closing its side, as an idle client would
+	defer conn.Close()
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	start := time.Now()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("took %s to shut down, want open connections ended rather than waited for", elapsed)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Listen did not return with a connection open")
+	}
+}
@@ -0,0 +1,37 @@
+package reqtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// reflection describes a received request, written as the response with reflect
+type reflection struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Query      url.Values  `json:"query"`
+	Headers    http.Header `json:"headers"`
+	BodyLength int64       `json:"body_length"`
+}
+
+// writeReflection responds to r with status and a JSON description of the request, with bodyLength as the bytes read from its body
+func (l *listener) writeReflection(w http.ResponseWriter, r *http.Request, status int, bodyLength int64) {
+	b, err := json.Marshal(reflection{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Headers:    r.Header,
+		BodyLength: bodyLength,
+	})
+	if err != nil {
+		l.logger.Error(fmt.Sprintf("error encoding reflected request: %v", err), "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
@@ -0,0 +1,213 @@
+package reqtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ReplayRequest is a recorded request to replay against the address being sent to
+type ReplayRequest struct {
+	Method string `json:"method"`
+	// URL is the recorded URL of the request. Only its path and query are replayed, against the scheme and host being sent to
+	URL    string      `json:"url"`
+	Header http.Header `json:"headers"`
+	Body   string      `json:"body"`
+	// Size is the size of the recorded body. Bodies recorded as a prefix shorter than Size are padded with a generated payload
+	Size int `json:"size"`
+}
+
+// ParseReplay parses recorded requests from either a HAR file, such as one recorded by listen, or JSON lines of ReplayRequest
+func ParseReplay(r io.Reader) ([]ReplayRequest, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read replay file: %w", err)
+	}
+
+	var har harFile
+	if err := json.Unmarshal(b, &har); err == nil && har.Log.Version != "" {
+		return harReplayRequests(har)
+	}
+
+	var requests []ReplayRequest
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var req ReplayRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return nil, fmt.Errorf("could not parse replay file line %v: %w", line, err)
+		}
+
+		requests = append(requests, req)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read replay file: %w", err)
+	}
+
+	if len(requests) == 0 {
+		return nil, errors.New("replay file has no requests")
+	}
+
+	return requests, nil
+}
+
+// harReplayRequests returns the requests of the entries of har, decoding base64 encoded bodies
+func harReplayRequests(har harFile) ([]ReplayRequest, error) {
+	requests := make([]ReplayRequest, 0, len(har.Log.Entries))
+	for i, entry := range har.Log.Entries {
+		req := ReplayRequest{
+			Method: entry.Request.Method,
+			URL:    entry.Request.URL,
+			Header: make(http.Header),
+			Size:   int(max(entry.Request.BodySize, 0)),
+		}
+
+		for _, h := range entry.Request.Headers {
+			req.Header.Add(h.Name, h.Value)
+		}
+
+		if data := entry.Request.PostData; data != nil {
+			req.Body = data.Text
+			if data.Encoding == "base64" {
+				body, err := base64.StdEncoding.DecodeString(data.Text)
+				if err != nil {
+					return nil, fmt.Errorf("could not decode body of HAR entry %v: %w", i+1, err)
+				}
+
+				req.Body = string(body)
+			}
+		}
+
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// replayHeaderSkip are recorded headers that describe the original connection or body encoding rather than the request,
+// and are set by the client for the replayed request instead
+var replayHeaderSkip = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+}
+
+// replay sends each recorded request in order, stopping at the first failure unless continueOnError is set
+func (s *sender) replay(ctx context.Context, requests []ReplayRequest, continueOnError bool) (Results, error) {
+	base, err := url.Parse(s.address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	stats := &sendStats{}
+	defer stats.logSummary(s.logger)
+	results := make(Results, 0, len(requests))
+	var failed []error
+	for i, req := range requests {
+		if i > 0 {
+			s.pause(ctx)
+		}
+
+		if ctx.Err() != nil {
+			return results, cancelledError(ctx, i, len(requests))
+		}
+
+		target, err := replayURL(base, req.URL)
+		if err != nil {
+			return results, fmt.Errorf("invalid url for replayed request %v: %w", i+1, err)
+		}
+
+		body := []byte(req.Body)
+		if len(body) < req.Size {
+			padding, err := s.payload(req.Size - len(body))
+			if err != nil {
+				return results, err
+			}
+
+			s.logger.Info(fmt.Sprintf("padding recorded body of %v bytes to %v bytes", len(body), req.Size), "recorded", len(body), "size", req.Size)
+			body = append(body, padding...)
+		}
+
+		header := make(http.Header)
+		for key, values := range req.Header {
+			if !replayHeaderSkip[http.CanonicalHeaderKey(key)] {
+				header[http.CanonicalHeaderKey(key)] = values
+			}
+		}
+
+		method := strings.ToUpper(req.Method)
+		if method == "" {
+			method = s.method
+		}
+
+		s.logger.Info(fmt.Sprintf("replaying %v %v with %v bytes", method, target, len(body)), "method", method, "url", target, "size", len(body))
+		result, err := s.sendBody(ctx, method, target, header, body)
+		if errors.Is(err, errBudgetExhausted) {
+			break
+		}
+
+		if err != nil && ctx.Err() != nil {
+			return results, cancelledError(ctx, i, len(requests))
+		}
+
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("replayed request %v of %v failed: %v", i+1, len(requests), err), "method", method, "url", target, "size", len(body), "status", result.StatusCode, "error", err, "error_kind", result.ErrorKind)
+			result.Error = err.Error()
+			results = append(results, result)
+			failed = append(failed, err)
+			if !continueOnError {
+				return results, fmt.Errorf("replayed request %v of %v failed: %w", i+1, len(requests), err)
+			}
+
+			continue
+		}
+
+		results = append(results, result)
+		stats.record(result.Size, result.Duration)
+		s.logger.Info(fmt.Sprintf("replayed %v %v in %s", method, target, result.Duration), "method", method, "url", target, "size", len(body), "status", result.StatusCode, "duration", result.Duration)
+	}
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%v of %v replayed requests failed: %w", len(failed), len(requests), failed[0])
+	}
+
+	return results, nil
+}
+
+// replayURL returns the recorded URL's path and query joined onto the path and query of base, which include any Path and
+// Query from the config, so replayed requests can be sent under a prefix such as /upload with extra query parameters
+func replayURL(base *url.URL, recorded string) (string, error) {
+	u, err := url.Parse(recorded)
+	if err != nil {
+		return "", err
+	}
+
+	target := base.JoinPath(u.EscapedPath())
+	if base.Path == "" || base.Path == "/" {
+		target.Path, target.RawPath = u.Path, u.RawPath
+	}
+
+	switch {
+	case base.RawQuery == "":
+		target.RawQuery = u.RawQuery
+	case u.RawQuery != "":
+		target.RawQuery = base.RawQuery + "&" + u.RawQuery
+	}
+
+	return target.String(), nil
+}
@@ -0,0 +1,35 @@
+package reqtest
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestReplayURL(t *testing.T) {
+	tests := []struct {
+		base, recorded, want string
+	}{
+		{base: "http://host:8080", recorded: "http://other/upload/1?x=1", want: "http://host:8080/upload/1?x=1"},
+		{base: "http://host:8080/", recorded: "/upload/1", want: "http://host:8080/upload/1"},
+		{base: "http://host:8080/api", recorded: "http://other/upload/1?x=1", want: "http://host:8080/api/upload/1?x=1"},
+		{base: "http://host:8080/api?token=a", recorded: "/upload/1?x=1", want: "http://host:8080/api/upload/1?token=a&x=1"},
+		{base: "http://host:8080/api?token=a", recorded: "/upload/1", want: "http://host:8080/api/upload/1?token=a"},
+		{base: "http://host:8080", recorded: "/a%2Fb", want: "http://host:8080/a%2Fb"},
+	}
+
+	for _, tt := range tests {
+		base, err := url.Parse(tt.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := replayURL(base, tt.recorded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Errorf("replayURL(%v, %v) = %v, want %v", tt.base, tt.recorded, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package reqtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// requestLog appends a JSON object per handled request to a file as each request completes. Each line is written to the
+// file in a single unbuffered write, so the log can be tailed while the listener is running
+type requestLog struct {
+	logger *slog.Logger
+	mu     sync.Mutex
+	f      *os.File
+	enc    *json.Encoder
+	closed bool
+}
+
+// requestLogEntry is a line of the request log
+type requestLogEntry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	BodySize   int64     `json:"body_size"`
+	Status     int       `json:"status"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// openRequestLog opens path to append request log lines to, creating it if needed
+func openRequestLog(path string, logger *slog.Logger) (*requestLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open log-file: %w", err)
+	}
+
+	return &requestLog{logger: logger, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// record wraps next so a line describing each request it handles is written once next returns
+func (l *requestLog) record(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		body := &countingBody{ReadCloser: r.Body}
+		r.Body = body
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rw, r)
+		l.write(requestLogEntry{
+			Time:       start,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+			BodySize:   body.n,
+			Status:     rw.status,
+			DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+		})
+	}
+}
+
+func (l *requestLog) write(entry requestLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+
+	if err := l.enc.Encode(entry); err != nil {
+		l.logger.Error(fmt.Sprintf("error writing to log-file: %v", err), "path", l.f.Name(), "error", err)
+	}
+}
+
+// close closes the file once, so it can be deferred as well as called once the listener has shut down
+func (l *requestLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+
+	l.closed = true
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("could not close log-file: %w", err)
+	}
+
+	return nil
+}
+
+// countingBody counts the bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,69 @@
+package reqtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// ParseResponses parses a JSON array of responses to serve in order, each in the same form as a route's response, for
+// example [{"status": 201, "body": "created"}, {"status": 409, "delay": "50ms", "headers": {"Retry-After": "1"}}]
+func ParseResponses(r io.Reader) ([]Route, error) {
+	var raw []routeJSON
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("could not parse responses: %w", err)
+	}
+
+	if len(raw) == 0 {
+		return nil, errors.New("responses must contain at least one response")
+	}
+
+	responses := make([]Route, len(raw))
+	for i, rr := range raw {
+		response, err := rr.route(fmt.Sprintf("response %v", i+1))
+		if err != nil {
+			return nil, err
+		}
+
+		responses[i] = response
+	}
+
+	return responses, nil
+}
+
+// responseSequence hands out a fixed sequence of responses one per request, looping back to the first once exhausted unless strict
+type responseSequence struct {
+	responses []Route
+	strict    bool
+	count     atomic.Uint64
+}
+
+// next returns the index of the response to serve, or false once a strict sequence is exhausted
+func (s *responseSequence) next() (int, bool) {
+	i := s.count.Add(1) - 1
+	if s.strict && i >= uint64(len(s.responses)) {
+		return 0, false
+	}
+
+	return int(i % uint64(len(s.responses))), true
+}
+
+// sequenceHandler serves each request the next response in the sequence regardless of its content, handing requests to
+// fallback once a strict sequence is exhausted
+func (l *listener) sequenceHandler(seq *responseSequence, fallback http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		i, ok := seq.next()
+		if !ok {
+			fallback(w, r)
+			return
+		}
+
+		l.serveCanned(w, r, seq.responses[i], fmt.Sprintf("for response %v of %v", i+1, len(seq.responses)), "response", i+1)
+		if seq.strict && i == len(seq.responses)-1 {
+			l.logger.Info(fmt.Sprintf("served all %v responses, handling later requests as usual", len(seq.responses)), "responses", len(seq.responses))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// sendBody makes a request with body, retrying retryable failures up to the configured number of retries
+func (s *sender) sendBody(ctx context.Context, method, address string, header http.Header, body []byte) (Result, error) {
+	return s.withRetries(ctx, len(body), func() (Result, error) { return s.attempt(ctx, method, address, header, body) })
+}
+
+// withRetries makes a request of size bytes with attempt, retrying retryable failures up to the configured number of retries.
+// The wait before each retry doubles, starting at the configured backoff
+func (s *sender) withRetries(ctx context.Context, size int, attempt func() (Result, error)) (Result, error) {
+	result, err := attempt()
+	retry := 0
+	for ; err != nil && retry < s.retries && ctx.Err() == nil && isRetryable(result, err); retry++ {
+		backoff := s.backoff << retry
+		s.logger.Warn(fmt.Sprintf("request of %v bytes failed, retrying in %s (retry %v of %v): %v", size, backoff, retry+1, s.retries, err), "size", size, "retry", retry+1, "backoff", backoff, "error", err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return result, err
+		case <-t.C:
+		}
+
+		retryResult, retryErr := attempt()
+		if errors.Is(retryErr, errBudgetExhausted) {
+			// the retry wasn't sent, so the request failed with the error of the last attempt that was
+			break
+		}
+
+		result, err = retryResult, retryErr
+	}
+
+	result.Retries = retry
+	if errors.Is(err, errBudgetExhausted) {
+		return result, err
+	}
+
+	if err != nil {
+		result.ErrorKind = classifyError(err)
+	}
+
+	s.progress.record(size, err)
+	if retry > 0 {
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("request of %v bytes failed after %v retries", size, retry), "size", size, "retries", retry, "error", err)
+		} else {
+			s.logger.Info(fmt.Sprintf("request of %v bytes succeeded after %v retries", size, retry), "size", size, "retries", retry)
+		}
+	}
+
+	return result, err
+}
+
+// isRetryable reports whether a failed request may succeed if sent again. Server errors, timeouts, rate limiting, and dropped
+// connections are retryable, while other client errors, redirects, integrity mismatches, and errors building the request are not
+func isRetryable(result Result, err error) bool {
+	if errors.Is(err, ErrIntegrityMismatch) {
+		return false
+	}
+
+	if result.StatusCode != 0 {
+		return result.StatusCode >= 500 || result.StatusCode == http.StatusRequestTimeout || result.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,144 @@
+package reqtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Route is a canned response for requests matching a route's pattern
+type Route struct {
+	// Status is the status code to respond with. Defaults to 200
+	Status int
+	// Delay is how long to wait before responding
+	Delay time.Duration
+	// Body is written as the response body
+	Body string
+	// Header is added to the response
+	Header http.Header
+}
+
+// ParseRoutes parses a JSON object mapping http.ServeMux patterns, such as "POST /upload/{id}", to the response for requests
+// matching them, for example {"POST /upload/{id}": {"status": 201, "delay": "100ms", "body": "created", "headers": {"X-Id": "1"}}}
+func ParseRoutes(r io.Reader) (map[string]Route, error) {
+	var raw map[string]routeJSON
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("could not parse routes: %w", err)
+	}
+
+	routes := make(map[string]Route, len(raw))
+	for pattern, rr := range raw {
+		route, err := rr.route(fmt.Sprintf("route %q", pattern))
+		if err != nil {
+			return nil, err
+		}
+
+		routes[pattern] = route
+	}
+
+	return routes, nil
+}
+
+// routeJSON is a Route as written in JSON, with its delay as a duration string
+type routeJSON struct {
+	Status  int               `json:"status"`
+	Delay   string            `json:"delay"`
+	Body    string            `json:"body"`
+	Headers map[string]string `json:"headers"`
+}
+
+// route validates rr and converts it to a Route, naming it by name in errors
+func (rr routeJSON) route(name string) (Route, error) {
+	route := Route{Status: rr.Status, Body: rr.Body, Header: make(http.Header)}
+	if rr.Delay != "" {
+		delay, err := time.ParseDuration(rr.Delay)
+		if err != nil {
+			return Route{}, fmt.Errorf("invalid delay for %v: %w", name, err)
+		}
+
+		route.Delay = delay
+	}
+
+	if route.Status != 0 && (route.Status < 100 || route.Status > 999) {
+		return Route{}, fmt.Errorf("invalid status %v for %v, must be a 3 digit status code", route.Status, name)
+	}
+
+	for key, value := range rr.Headers {
+		route.Header.Set(key, value)
+	}
+
+	return route, nil
+}
+
+// registerRoutes registers a handler for each route on mux, each wrapped by wrap. Patterns that are invalid or conflict with
+// another route or the listener's own handlers are returned as errors rather than panicking like http.ServeMux
+func (l *listener) registerRoutes(mux *http.ServeMux, routes map[string]Route, wrap func(http.HandlerFunc) http.HandlerFunc) (err error) {
+	patterns := make([]string, 0, len(routes))
+	for pattern := range routes {
+		patterns = append(patterns, pattern)
+	}
+
+	slices.Sort(patterns)
+	for _, pattern := range patterns {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("invalid route %q: %v", pattern, r)
+				}
+			}()
+
+			mux.HandleFunc(pattern, wrap(l.routeHandler(pattern, routes[pattern])))
+		}()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// routeHandler responds to requests matching pattern with route's canned response, discarding the request body
+func (l *listener) routeHandler(pattern string, route Route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.serveCanned(w, r, route, fmt.Sprintf("matching route %v", pattern), "route", pattern)
+	}
+}
+
+// serveCanned responds to r with route's canned response, discarding the request body. matched describes why the route was
+// chosen in logs, and attrs are added to them
+func (l *listener) serveCanned(w http.ResponseWriter, r *http.Request, route Route, matched string, attrs ...any) {
+	done, ok := l.admit(w)
+	if !ok {
+		return
+	}
+
+	defer done()
+	l.requests.Add(1)
+	l.inFlight.Add(1)
+	defer l.inFlight.Add(-1)
+	n, err := l.copyBody(io.Discard, r.Body)
+	if err != nil {
+		l.logger.Error(fmt.Sprintf("error reading body: %v", err), append(attrs, "error", err)...)
+	}
+
+	l.logger.Info(fmt.Sprintf("received request %v, read %v bytes from body", matched, n), append(attrs, "method", r.Method, "path", r.URL.Path, "size", n)...)
+	if route.Delay > 0 {
+		time.Sleep(route.Delay)
+	}
+
+	for key, values := range route.Header {
+		w.Header()[key] = values
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	io.WriteString(w, route.Body)
+}
@@ -0,0 +1,1019 @@
+package reqtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	StepModePow2   = "pow2"
+	StepModeLinear = "linear"
+)
+
+// validMethods are the HTTP methods accepted by SendConfig.Method
+var validMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// SendConfig configures the requests made by Send
+type SendConfig struct {
+	// Address is the URL to send requests to, or a unix domain socket such as unix:/tmp/reqtest.sock to send requests over
+	Address string
+	// Method is the HTTP method to use for requests. Defaults to PUT
+	Method string
+	// StartStep and EndStep bound the payload sizes sent, in powers of 2. They default to 1 and 25
+	StartStep int
+	EndStep   int
+	// StepMode is how payload sizes increase between requests, either StepModePow2 or StepModeLinear. Defaults to StepModePow2
+	StepMode string
+	// StepSize is the number of bytes added to each payload with StepModeLinear
+	StepSize int
+	// Repeat is the number of times to send each payload size. Defaults to 1
+	Repeat int
+	// Iterations is the number of times to run the whole ramp of sizes, each size being sent Repeat times per iteration. Defaults to 1
+	Iterations int
+	// Concurrency is the number of concurrent workers sending requests. Defaults to 1
+	Concurrency int
+	// ContinueOnError keeps sending the remaining sizes after a request fails, reporting every failure at the end
+	ContinueOnError bool
+	// Timeout limits how long each request may take, 0 for no limit
+	Timeout time.Duration
+	// Verbose logs whether each request reused a connection, the remote address, and the protocol used
+	Verbose bool
+	// Trace logs how long DNS lookup, connecting, the TLS handshake, and the first response byte took for each request
+	Trace bool
+	// Propagate injects W3C traceparent and tracestate headers for a new trace into each request, logging the trace IDs
+	Propagate bool
+	// NoKeepAlive disables keep-alives so every request is sent over a new connection
+	NoKeepAlive bool
+	// MaxConnsPerHost limits how many connections may be open to the host at once, 0 for no limit
+	MaxConnsPerHost int
+	// ReadResponse reads and discards all of each response body before closing it so its connection can be reused. Otherwise
+	// only the first 64KiB of bodies not read to verify are drained, and larger bodies stop HTTP/1.1 connections from being pooled
+	ReadResponse bool
+	// ExpectContinue sends requests with Expect: 100-continue, so the body is only sent once the server responds with 100 Continue
+	ExpectContinue bool
+	// RawTCP writes each payload over a new plain TCP connection to Address, a host:port, rather than sending an HTTP request.
+	// The response is read until EOF or ResponseBytes, and is compared with the payload when Verify is set
+	RawTCP bool
+	// ResponseBytes stops reading a RawTCP response after this many bytes, 0 to read until the connection is closed
+	ResponseBytes int64
+	// ClientCert and ClientKey are paths to a certificate and private key to present to servers requesting client authentication
+	ClientCert string
+	ClientKey  string
+	// CACert is a path to PEM encoded CA certificates to trust instead of the system roots
+	CACert string
+	// Insecure skips verifying the server's certificate, for self-signed test servers
+	Insecure bool
+	// Proxy is an http, https, or socks5 URL to send requests through. Defaults to the proxy configured by the environment
+	Proxy string
+	// HTTP2 sends requests over HTTP/2 only, using h2c with prior knowledge for http and unix domain socket addresses
+	HTTP2 bool
+	// HTTP3 sends requests over HTTP/3 only, using QUIC over UDP. QUIC is always encrypted with TLS 1.3, so Address must be an
+	// https URL of a server serving HTTP/3
+	HTTP3 bool
+	// ExpectStatus are the response status codes treated as success, any other status fails the request. Defaults to 200
+	ExpectStatus []int
+	// NoRedirect reports redirect responses as the result of a request rather than following them
+	NoRedirect bool
+	// Force sends generated payloads larger than 256MiB, which are otherwise refused as they can exhaust a listener's memory
+	Force bool
+	// DryRun logs the sizes, target, method, and headers of the requests that would be made without sending any of them
+	DryRun bool
+	// Warmup is the number of throwaway requests of the first payload size to send before the measured run so connections are
+	// established and pooled. They are spread across the workers, sent without retries, and excluded from results and stats
+	Warmup int
+	// Duration, if set, repeatedly sends the starting payload size until it elapses instead of stepping through sizes
+	Duration time.Duration
+	// Payload, if non-nil, is sent as the request body instead of generated payloads, ignoring the step settings
+	Payload []byte
+	// Sizes, if set, are the payload sizes to send in order instead of stepping, ignoring the step settings. Their payloads are
+	// streamed as with Stream unless Gzip, MultipartField, Verify, or RawTCP need each one in full
+	Sizes []int
+	// Stream generates each payload as the request body is read instead of allocating it up front, so memory use stays flat
+	// with payloads of any size. The generated bytes follow Pattern and Raw
+	Stream bool
+	// Template, if set, is a text/template rendered as the body of each request instead of generated payloads, ignoring the
+	// step settings. It can use {{.Index}}, a count of requests from 0, {{.UUID}}, a random UUID, and {{.Timestamp}}
+	Template string
+	// Replay are recorded requests to send in order instead of generated payloads, see ParseReplay. Their recorded paths and
+	// queries are joined onto those of Address, after Path and Query
+	Replay []ReplayRequest
+	// Path is joined onto the path of Address, such as /upload
+	Path string
+	// Query is encoded and appended to the query of Address
+	Query url.Values
+	// UserAgent is the User-Agent header of every request, including retries and warm-up requests, unless Header sets one.
+	// Defaults to DefaultUserAgent
+	UserAgent string
+	// Cookies keeps the cookies set by responses in a jar and sends them with later requests, logging the names of cookies
+	// as they are received and sent
+	Cookies bool
+	// Header is added to every request. A Host header sets the host requests are sent with in place of that of Address
+	Header http.Header
+	// BearerToken, if set, is sent in the Authorization header of every request. Conflicts with BasicAuth
+	BearerToken string
+	// BasicAuth, if set, is a user:pass pair sent as basic auth with every request. Conflicts with BearerToken
+	BasicAuth string
+	// ContentType is the Content-Type header of requests. Defaults to application/octet-stream with Payload
+	ContentType string
+	// Chunked sends bodies with chunked transfer encoding rather than a Content-Length
+	Chunked bool
+	// MultipartField wraps each payload as a file under this field in a multipart/form-data body when set
+	MultipartField string
+	// Pattern is the payload pattern to generate, one of PatternRandom, PatternZeros or PatternRepeating. Defaults to PatternRandom
+	Pattern string
+	// Raw sends the random bytes as-is rather than hex encoding them
+	Raw bool
+	// Gzip compresses request bodies and sets the Content-Encoding header
+	Gzip bool
+	// AcceptGzip sends Accept-Encoding: gzip and decompresses gzip responses itself rather than leaving it to the transport,
+	// logging the compressed and decompressed response sizes. Gzip responses are read in full even without ReadResponse
+	AcceptGzip bool
+	// Verify compares a SHA-256 of each payload against the response body, for use against a listener echoing bodies
+	Verify bool
+	// Seed, if set, seeds payload generation so the same payloads are produced across runs.
+	// For reproducibility only, seeded payloads are not cryptographically random.
+	Seed *int64
+	// Retries is how many times to retry a request that failed with a retryable error, such as a 5xx response or a dropped connection
+	Retries int
+	// RetryBackoff is how long to wait before the first retry, doubling for each retry after. Defaults to 100ms
+	RetryBackoff time.Duration
+	// Interval is how long to wait between requests, 0 to send back to back
+	Interval time.Duration
+	// Jitter randomizes each Interval by up to this percent either way
+	Jitter float64
+	// Progress is how often to log running totals of completed and failed requests and throughput, 0 to not log progress
+	Progress time.Duration
+	// MaxTotalBytes stops sending before the body bytes sent across every request, including retries, would exceed it, counting
+	// gzip bodies as compressed. 0 for no limit
+	MaxTotalBytes int64
+	// Bandwidth limits how fast request bodies are written in bytes per second, 0 for no limit
+	Bandwidth int64
+	// Logger receives the per-request logs and summary. Defaults to text logs on stderr
+	Logger *slog.Logger
+}
+
+// Result is the outcome of a single request made by Send. MultipartSize is set when the payload was wrapped in a multipart body,
+// CompressedSize is set when the body was gzipped, Mismatch is set when a verified response body differed from the payload,
+// Retries is how many times the request was retried, Target is the address it was sent to when sent by SendTargets,
+// Iteration is which run of the ramp, starting at 1, the request was part of when sending multiple Iterations, and
+// ErrorKind classifies a failed request's Error as one of the ErrorKind constants.
+type Result struct {
+	Target         string        `json:"target,omitempty"`
+	Iteration      int           `json:"iteration,omitempty"`
+	Size           int           `json:"size"`
+	MultipartSize  int           `json:"multipart_size,omitempty"`
+	CompressedSize int           `json:"compressed_size,omitempty"`
+	Duration       time.Duration `json:"duration_ns"`
+	StatusCode     int           `json:"status_code,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	ErrorKind      string        `json:"error_kind,omitempty"`
+	Mismatch       bool          `json:"mismatch,omitempty"`
+	Retries        int           `json:"retries,omitempty"`
+}
+
+// ErrIntegrityMismatch is returned when a verified response body does not match the payload that was sent
+var ErrIntegrityMismatch = errors.New("response body does not match payload")
+
+// Results are the outcomes of each request made by Send, in the order they completed
+type Results []Result
+
+// Send makes requests of increasing payload sizes according to cfg until the sizes are exhausted or ctx is cancelled.
+// The results of any requests made are returned, even if an error occurs partway through the run.
+func Send(ctx context.Context, cfg SendConfig) (Results, error) {
+	method := http.MethodPut
+	if cfg.Method != "" {
+		method = strings.ToUpper(cfg.Method)
+	}
+
+	if err := validateMethod(method); err != nil {
+		return nil, err
+	}
+
+	if cfg.BearerToken != "" && cfg.BasicAuth != "" {
+		return nil, errors.New("bearer and basic auth cannot both be used")
+	}
+
+	if cfg.BasicAuth != "" && !strings.Contains(cfg.BasicAuth, ":") {
+		return nil, errors.New("basic auth must be in the form user:pass")
+	}
+
+	if cfg.MultipartField != "" && cfg.ContentType != "" {
+		return nil, errors.New("content-type cannot be used with multipart, which sets its own content type")
+	}
+
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 100 * time.Millisecond
+	}
+
+	if cfg.Jitter < 0 || cfg.Jitter > 100 {
+		return nil, errors.New("jitter must be a percent between 0 and 100")
+	}
+
+	if len(cfg.Replay) > 0 && (cfg.Payload != nil || cfg.Duration > 0 || cfg.Concurrency > 1 || cfg.Warmup > 0) {
+		return nil, errors.New("replay cannot be used with a payload, duration, concurrency, or warmup")
+	}
+
+	if len(cfg.Sizes) > 0 && (cfg.Payload != nil || cfg.Template != "" || len(cfg.Replay) > 0 || cfg.Duration > 0) {
+		return nil, errors.New("sizes cannot be used with a payload, template, replay, or duration")
+	}
+
+	if cfg.Stream && (cfg.Payload != nil || cfg.Template != "" || len(cfg.Replay) > 0 || cfg.MultipartField != "" || cfg.Gzip || cfg.Verify || cfg.RawTCP) {
+		return nil, errors.New("stream cannot be used with a payload, template, replay, multipart, gzip, verify, or raw-tcp")
+	}
+
+	if cfg.Template != "" && (cfg.Payload != nil || len(cfg.Replay) > 0) {
+		return nil, errors.New("template cannot be used with a payload or replay")
+	}
+
+	if cfg.Iterations > 1 && (len(cfg.Replay) > 0 || cfg.Duration > 0) {
+		return nil, errors.New("iterations cannot be used with replay or duration")
+	}
+
+	_, unix := unixSocketPath(cfg.Address)
+	if cfg.HTTP3 && !strings.HasPrefix(cfg.Address, "https://") {
+		return nil, errors.New("http3 requires an https address, as QUIC is always encrypted with TLS")
+	}
+
+	if cfg.HTTP3 && (cfg.HTTP2 || cfg.RawTCP || cfg.Proxy != "" || cfg.NoKeepAlive || cfg.MaxConnsPerHost > 0 || cfg.ExpectContinue) {
+		return nil, errors.New("http3 cannot be used with http2, raw-tcp, proxy, disable-keepalive, max-conns-per-host, or expect-continue")
+	}
+
+	if cfg.Proxy != "" && (unix || cfg.HTTP2) {
+		return nil, errors.New("proxy cannot be used with http2 or a unix socket address")
+	}
+
+	if cfg.RawTCP && (len(cfg.Replay) > 0 || cfg.MultipartField != "" || cfg.Chunked || cfg.Gzip || cfg.AcceptGzip || cfg.HTTP2 || cfg.Proxy != "" || cfg.Path != "" || len(cfg.Query) > 0 || unix) {
+		return nil, errors.New("raw-tcp cannot be used with replay, multipart, chunked, gzip, accept-gzip, http2, proxy, path, query, or a unix socket address")
+	}
+
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, errors.New("client-cert and client-key must both be provided to present a client certificate")
+	}
+
+	clientTLS := cfg.ClientCert != "" || cfg.CACert != "" || cfg.Insecure
+	if clientTLS && cfg.RawTCP {
+		return nil, errors.New("client-cert, ca-cert, and insecure cannot be used with raw-tcp")
+	}
+
+	tune := cfg.NoKeepAlive || cfg.MaxConnsPerHost > 0
+	if tune && (cfg.HTTP2 || cfg.RawTCP) {
+		return nil, errors.New("disable-keepalive and max-conns-per-host cannot be used with http2 or raw-tcp")
+	}
+
+	for _, code := range cfg.ExpectStatus {
+		if code < 100 || code > 999 {
+			return nil, fmt.Errorf("invalid expect-status %v, must be a 3 digit status code", code)
+		}
+	}
+
+	if len(cfg.ExpectStatus) > 0 && cfg.RawTCP {
+		return nil, errors.New("expect-status cannot be used with raw-tcp")
+	}
+
+	if cfg.ExpectStatus == nil {
+		cfg.ExpectStatus = []int{http.StatusOK}
+	}
+
+	if cfg.ExpectContinue && (cfg.HTTP2 || cfg.RawTCP) {
+		return nil, errors.New("expect-continue cannot be used with http2 or raw-tcp")
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = defaultLogger()
+	}
+
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent()
+	}
+
+	if method == http.MethodGet {
+		cfg.Logger.Warn(fmt.Sprintf("warning: sending a body with %v, the server will likely ignore it", method), "method", method)
+	}
+
+	s := &sender{
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		logger:      cfg.Logger,
+		method:      method,
+		address:     cfg.Address,
+		header:      cfg.Header,
+		userAgent:   cfg.UserAgent,
+		bearerToken: cfg.BearerToken,
+		basicAuth:   cfg.BasicAuth,
+		contentType: cfg.ContentType,
+		multipart:   cfg.MultipartField,
+		chunked:     cfg.Chunked,
+		interval:    cfg.Interval,
+		retries:     cfg.Retries,
+		backoff:     cfg.RetryBackoff,
+		jitter:      cfg.Jitter,
+		bandwidth:   cfg.Bandwidth,
+		gzip:        cfg.Gzip,
+		acceptGzip:  cfg.AcceptGzip,
+		verify:      cfg.Verify,
+		okStatus:    cfg.ExpectStatus,
+	}
+
+	s.client.CheckRedirect = s.checkRedirect(cfg.NoRedirect)
+	if cfg.RawTCP {
+		address, err := rawTCPAddress(cfg.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		s.address = address
+		s.rawTCP = true
+		s.respBytes = cfg.ResponseBytes
+	}
+
+	var unixPath string
+	if path, ok := unixSocketPath(cfg.Address); ok {
+		unixPath = path
+		s.client.Transport = unixTransport(path)
+		s.address = "http://localhost/"
+	}
+
+	if cfg.HTTP2 {
+		s.client.Transport = newHTTP2Transport(s.address, unixPath)
+		s.logProto = true
+	}
+
+	if cfg.HTTP3 {
+		transport := newHTTP3Transport()
+		defer transport.Close()
+		s.client.Transport = transport
+		s.logProto = true
+	}
+
+	if cfg.Path != "" {
+		address, err := withPath(s.address, cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		s.address = address
+	}
+
+	if len(cfg.Query) > 0 {
+		address, err := withQuery(s.address, cfg.Query)
+		if err != nil {
+			return nil, err
+		}
+
+		s.address = address
+	}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := parseProxy(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		s.client.Transport = proxyTransport(proxyURL)
+		s.logger.Info(fmt.Sprintf("using proxy %v", proxyURL.Redacted()), "proxy", proxyURL.Redacted())
+	} else if !unix && !cfg.HTTP2 && !cfg.HTTP3 && !cfg.RawTCP {
+		if proxyURL := environmentProxy(s.address); proxyURL != nil {
+			s.logger.Info(fmt.Sprintf("using proxy %v from the environment", proxyURL.Redacted()), "proxy", proxyURL.Redacted())
+		}
+	}
+
+	if tune {
+		s.client.Transport = tuneTransport(s.logger, s.client.Transport, cfg.NoKeepAlive, cfg.MaxConnsPerHost)
+	}
+
+	if cfg.ExpectContinue {
+		s.client.Transport = expectContinueTransport(s.client.Transport)
+		s.expect = true
+	}
+
+	if clientTLS {
+		config, err := clientTLSConfig(cfg.ClientCert, cfg.ClientKey, cfg.CACert, cfg.Insecure)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Insecure {
+			s.logger.Warn("warning: skipping verification of the server's TLS certificate")
+		}
+
+		s.client.Transport = withTLSClientConfig(s.client.Transport, config)
+	}
+
+	if cfg.Cookies {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create cookie jar: %w", err)
+		}
+
+		s.client.Jar = jar
+	}
+
+	s.readResp = cfg.ReadResponse
+	s.verbose = cfg.Verbose
+	s.trace = cfg.Trace
+	s.propagate = cfg.Propagate
+
+	var src io.Reader = rand.Reader
+	if cfg.Seed != nil {
+		src = newSeededReader(*cfg.Seed)
+	}
+
+	pattern := PatternRandom
+	if cfg.Pattern != "" {
+		pattern = cfg.Pattern
+	}
+
+	payload, err := newPayloadGenerator(pattern, src, cfg.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	s.payload = payload
+	// sizes from a file can be arbitrarily large, so they are streamed whenever the whole payload isn't needed
+	stream := cfg.Stream || len(cfg.Sizes) > 0 && !cfg.Gzip && cfg.MultipartField == "" && !cfg.Verify && !cfg.RawTCP
+	if stream {
+		stream, err := newStreamGenerator(pattern, src, cfg.Raw)
+		if err != nil {
+			return nil, err
+		}
+
+		s.stream = stream
+	}
+
+	if cfg.MaxTotalBytes > 0 {
+		s.budget = &byteBudget{logger: s.logger, limit: cfg.MaxTotalBytes}
+	}
+
+	var sizes []int
+	if cfg.Payload != nil {
+		if s.contentType == "" && s.multipart == "" {
+			s.contentType = "application/octet-stream"
+		}
+
+		s.payload = fixedPayload(cfg.Payload)
+		sizes = []int{len(cfg.Payload)}
+	} else if cfg.Template != "" {
+		if s.contentType == "" && s.multipart == "" {
+			s.contentType = "application/octet-stream"
+		}
+
+		generator, size, err := templatePayload(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+
+		s.payload = generator
+		sizes = []int{size}
+	} else if len(cfg.Sizes) > 0 {
+		sizes = cfg.Sizes
+	} else if len(cfg.Replay) == 0 {
+		var err error
+		sizes, err = stepSizes(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.checkLargePayloads(cfg, sizes); err != nil {
+		return nil, err
+	}
+
+	if cfg.DryRun {
+		s.logPlan(ctx, cfg, sizes)
+		return nil, nil
+	}
+
+	if cfg.Warmup > 0 {
+		s.warmUp(ctx, cfg.Warmup, sizes[0], max(cfg.Concurrency, 1))
+	}
+
+	// connections are tracked after warming up so the stats only cover the measured run
+	s.conns = &connStats{}
+	// QUIC connections aren't reported to httptrace, so there are no connections to summarize with HTTP/3
+	if !cfg.RawTCP && !cfg.HTTP3 {
+		defer s.conns.logSummary(s.logger, cfg.MaxConnsPerHost)
+	}
+
+	if cfg.Progress > 0 {
+		progressCtx, stopProgress := context.WithCancel(ctx)
+		defer stopProgress()
+		go s.logProgress(progressCtx, cfg.Progress)
+	}
+
+	if len(cfg.Replay) > 0 {
+		return s.replay(ctx, cfg.Replay, cfg.ContinueOnError)
+	}
+
+	if cfg.Duration > 0 {
+		return s.sendForDuration(ctx, sizes[0], cfg.Duration, max(cfg.Concurrency, 1))
+	}
+
+	if cfg.Iterations > 1 {
+		return s.iterate(ctx, cfg, sizes, cfg.Iterations)
+	}
+
+	return s.ramp(ctx, cfg, sizes)
+}
+
+// ramp sends each of sizes cfg.Repeat times, sequentially or across cfg.Concurrency workers, and logs the latency stats
+func (s *sender) ramp(ctx context.Context, cfg SendConfig, sizes []int) (Results, error) {
+	repeat := max(cfg.Repeat, 1)
+	stats := &sendStats{}
+	defer stats.logSummary(s.logger)
+	if cfg.Concurrency > 1 {
+		results, err := s.sendConcurrently(ctx, sizes, repeat, cfg.Concurrency, cfg.ContinueOnError)
+		sizeStats := make(map[int]*sendStats)
+		for _, result := range results {
+			if result.Error != "" {
+				continue
+			}
+
+			stats.record(result.Size, result.Duration)
+			// bodies rendered from a template vary in size, so they're grouped under its single nominal size
+			size := result.Size
+			if len(sizes) == 1 {
+				size = sizes[0]
+			}
+
+			if sizeStats[size] == nil {
+				sizeStats[size] = &sendStats{}
+			}
+
+			sizeStats[size].record(result.Size, result.Duration)
+		}
+
+		if repeat > 1 {
+			for _, size := range sizes {
+				if sizeStats[size] != nil {
+					sizeStats[size].logPercentiles(s.logger, size)
+				}
+			}
+		}
+
+		return results, err
+	}
+
+	results := make(Results, 0, len(sizes)*repeat)
+	failures := make(sizeFailures)
+sizes:
+	for completed, bytesToSend := range sizes {
+		sizeStats := &sendStats{}
+		sizeErrs := make([]error, 0)
+		for i := 0; i < repeat; i++ {
+			if completed > 0 || i > 0 {
+				s.pause(ctx)
+			}
+
+			if ctx.Err() != nil {
+				return results, cancelledError(ctx, completed, len(sizes))
+			}
+
+			s.logger.Info(fmt.Sprintf("sending %v bytes", bytesToSend), "size", bytesToSend)
+			result, err := s.send(ctx, bytesToSend)
+			if errors.Is(err, errBudgetExhausted) {
+				break sizes
+			}
+
+			if err != nil && ctx.Err() != nil {
+				return results, cancelledError(ctx, completed, len(sizes))
+			}
+
+			if err != nil {
+				s.logger.Error(fmt.Sprintf("request of %v bytes failed: %v", bytesToSend, err), "size", bytesToSend, "status", result.StatusCode, "error", err, "error_kind", result.ErrorKind)
+				result.Error = err.Error()
+				results = append(results, result)
+				sizeErrs = append(sizeErrs, err)
+				continue
+			}
+
+			results = append(results, result)
+			stats.record(bytesToSend, result.Duration)
+			sizeStats.record(bytesToSend, result.Duration)
+			s.logger.Info(fmt.Sprintf("sent %v bytes in %s", bytesToSend, result.Duration), "size", bytesToSend, "status", result.StatusCode, "duration", result.Duration)
+		}
+
+		if repeat > 1 {
+			sizeStats.logPercentiles(s.logger, bytesToSend)
+		}
+
+		if len(sizeErrs) > 0 {
+			failures[bytesToSend] = sizeErrs
+			if !cfg.ContinueOnError {
+				return results, failures.err(repeat)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		failures.logSummary(s.logger, len(sizes), repeat)
+		return results, failures.err(repeat)
+	}
+
+	return results, nil
+}
+
+// sender holds the configuration used to make each request in Send
+type sender struct {
+	client      *http.Client
+	logger      *slog.Logger
+	method      string
+	address     string
+	header      http.Header
+	bearerToken string
+	basicAuth   string
+	contentType string
+	multipart   string
+	chunked     bool
+	interval    time.Duration
+	jitter      float64
+	retries     int
+	backoff     time.Duration
+	progress    progress
+	payload     payloadGenerator
+	bandwidth   int64
+	gzip        bool
+	acceptGzip  bool
+	verify      bool
+	okStatus    []int
+	logProto    bool
+	verbose     bool
+	trace       bool
+	propagate   bool
+	rawTCP      bool
+	respBytes   int64
+	expect      bool
+	budget      *byteBudget
+	stream      streamGenerator
+	conns       *connStats
+	readResp    bool
+	userAgent   string
+}
+
+// checkRedirect either stops at the first redirect so it is reported, or logs each hop while following up to 10 redirects like the default client
+func (s *sender) checkRedirect(noRedirect bool) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if noRedirect {
+			return http.ErrUseLastResponse
+		}
+
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		from := via[len(via)-1].URL
+		s.logger.Info(fmt.Sprintf("following redirect from %v to %v", from, req.URL), "from", from.String(), "to", req.URL.String(), "status", req.Response.StatusCode)
+		return nil
+	}
+}
+
+// send makes a single request with a payload of the given size and returns the outcome of the request
+func (s *sender) send(ctx context.Context, size int) (Result, error) {
+	if s.stream != nil {
+		return s.withRetries(ctx, size, func() (Result, error) { return s.attemptStream(ctx, size) })
+	}
+
+	body, header, err := s.body(size)
+	if err != nil {
+		return Result{Size: size}, err
+	}
+
+	result, err := s.sendBody(ctx, s.method, s.address, header, body)
+	if s.multipart != "" {
+		result.MultipartSize = result.Size
+		result.Size = size
+	}
+
+	return result, err
+}
+
+// body generates a payload of size, wrapping it in a multipart body along with the header describing it when configured
+func (s *sender) body(size int) ([]byte, http.Header, error) {
+	body, err := s.payload(size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.multipart == "" {
+		return body, nil, nil
+	}
+
+	wrapped, contentType, err := multipartPayload(s.multipart, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.Info(fmt.Sprintf("wrapped %v bytes in a %v byte multipart body", size, len(wrapped)), "size", size, "multipart_size", len(wrapped))
+	return wrapped, http.Header{"Content-Type": {contentType}}, nil
+}
+
+// attempt makes a single request with body, adding header to the request before any configured headers
+func (s *sender) attempt(ctx context.Context, method, address string, header http.Header, body []byte) (Result, error) {
+	size := len(body)
+	result := Result{Size: size}
+
+	var sentSum [sha256.Size]byte
+	if s.verify {
+		sentSum = sha256.Sum256(body)
+	}
+
+	if s.gzip {
+		compressed, err := gzipPayload(body)
+		if err != nil {
+			return result, err
+		}
+
+		ratio := float64(len(compressed)) / float64(max(len(body), 1))
+		s.logger.Info(fmt.Sprintf("compressed %v bytes to %v bytes (%.1f%%)", len(body), len(compressed), ratio*100), "size", len(body), "compressed_size", len(compressed), "ratio", ratio)
+		result.CompressedSize = len(compressed)
+		body = compressed
+	}
+
+	// the budget counts the bytes sent, so a gzip body counts its compressed size
+	if !s.budget.reserve(int64(len(body))) {
+		return result, errBudgetExhausted
+	}
+
+	if s.rawTCP {
+		return s.attemptRaw(ctx, body)
+	}
+
+	return s.do(ctx, method, address, header, result, bytes.NewReader(body), int64(len(body)), sentSum)
+}
+
+// do makes a single request reading its body of contentLength bytes from bodyReader, for the payload result describes,
+// and verifies the response matches sentSum when configured
+func (s *sender) do(ctx context.Context, method, address string, header http.Header, result Result, bodyReader io.Reader, contentLength int64, sentSum [sha256.Size]byte) (Result, error) {
+	size := result.Size
+	if s.bandwidth > 0 {
+		bodyReader = newThrottledReader(bodyReader, s.bandwidth)
+	}
+
+	trace := &requestTrace{conns: s.conns}
+	if s.verbose || s.trace || s.expect || s.conns != nil {
+		ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	}
+
+	// deferred before the response is closed so the connection is only released once its body has been drained
+	defer func() {
+		if s.conns != nil && trace.conn.Conn != nil {
+			s.conns.release(trace.conn.Conn)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, address, bodyReader)
+	if err != nil {
+		return result, fmt.Errorf("could not make request: %w", err)
+	}
+
+	req.ContentLength = contentLength
+	if s.chunked {
+		// an unknown length with a body that doesn't reveal its size makes the transport use chunked transfer encoding
+		req.ContentLength = -1
+		req.Body = io.NopCloser(struct{ io.Reader }{bodyReader})
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// set after the recorded headers of replayed requests so they are also identifiable, unless a header overrides it
+	if s.header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", s.userAgent)
+	}
+
+	for key, values := range s.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// the client sends req.Host rather than any Host header, so one from a header, such as to route to a virtual host, is
+	// moved there, the last taking precedence
+	if hosts := req.Header.Values("Host"); len(hosts) > 0 {
+		req.Host = hosts[len(hosts)-1]
+		req.Header.Del("Host")
+	}
+
+	if s.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken)
+	}
+
+	if s.basicAuth != "" {
+		user, pass, _ := strings.Cut(s.basicAuth, ":")
+		req.SetBasicAuth(user, pass)
+	}
+
+	if s.contentType != "" {
+		req.Header.Set("Content-Type", s.contentType)
+	}
+
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if s.expect {
+		req.Header.Set("Expect", "100-continue")
+	}
+
+	// setting the header stops the transport from transparently decompressing the response, so its size can be measured
+	if s.acceptGzip && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	if s.propagate {
+		sc := injectTraceContext(req)
+		s.logger.Info(fmt.Sprintf("request of %v bytes started trace %v span %v", size, sc.TraceID(), sc.SpanID()), "size", size, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+
+	reqStart := time.Now()
+	if s.trace {
+		// deferred so the total includes reading the response body when verifying
+		defer func() {
+			trace.logBreakdown(s.logger, size, reqStart, result.Duration)
+		}()
+	}
+
+	if s.client.Jar != nil {
+		s.logCookies(size, req, nil)
+	}
+
+	resp, err := s.client.Do(req)
+	result.Duration = time.Since(reqStart)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return result, fmt.Errorf("request timed out after %s: %w", result.Duration.Round(time.Millisecond), err)
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("could not execute request: %w", err)
+	}
+
+	if s.acceptGzip {
+		gunzipResponse(s.logger, resp, size)
+	}
+
+	defer s.closeResponse(resp, size)
+	result.StatusCode = resp.StatusCode
+	if s.client.Jar != nil {
+		s.logCookies(size, req, resp)
+	}
+
+	if s.expect {
+		s.logContinue(size, trace, resp.StatusCode)
+	}
+
+	switch {
+	case s.verbose && trace.conn.Conn != nil:
+		reuse := "new"
+		if trace.conn.Reused {
+			reuse = "reused"
+		}
+
+		remote := trace.conn.Conn.RemoteAddr().String()
+		s.logger.Info(fmt.Sprintf("request of %v bytes used %v over a %v connection to %v", size, resp.Proto, reuse, remote), "size", size, "proto", resp.Proto, "reused", trace.conn.Reused, "remote_addr", remote)
+	case s.logProto || s.verbose:
+		s.logger.Info(fmt.Sprintf("request of %v bytes used %v", size, resp.Proto), "size", size, "proto", resp.Proto)
+	}
+
+	if s.chunked {
+		framing := "chunked transfer encoding"
+		if resp.ProtoMajor >= 2 {
+			framing = fmt.Sprintf("HTTP/%v data frames, which replace chunked transfer encoding", resp.ProtoMajor)
+		}
+
+		s.logger.Info(fmt.Sprintf("request of %v bytes sent with %v", size, framing), "size", size, "proto", resp.Proto, "chunked", resp.ProtoMajor == 1)
+	}
+
+	if !slices.Contains(s.okStatus, resp.StatusCode) {
+		return result, &statusError{code: resp.StatusCode, expected: s.okStatus}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Info(fmt.Sprintf("request of %v bytes accepted with status %v", size, resp.StatusCode), "size", size, "status", resp.StatusCode)
+	}
+
+	if s.verify {
+		h := sha256.New()
+		if _, err := io.Copy(h, resp.Body); err != nil {
+			return result, fmt.Errorf("could not read response body to verify: %w", err)
+		}
+
+		result.Duration = time.Since(reqStart)
+		if receivedSum := h.Sum(nil); !bytes.Equal(receivedSum, sentSum[:]) {
+			result.Mismatch = true
+			return result, fmt.Errorf("%w: sent sha256 %x, received %x", ErrIntegrityMismatch, sentSum, receivedSum)
+		}
+	}
+
+	return result, nil
+}
+
+// maxDrainBytes is how much of an unread response body is discarded before closing it, so small responses leave their
+// connection reusable without reading large ones, such as echoed payloads, in full
+const maxDrainBytes = 64 << 10
+
+// closeResponse drains and closes the body of resp, reading all of it when configured or up to maxDrainBytes otherwise.
+// Decompressed gzip responses are always read in full so the sizes logged on close are those of the whole body
+func (s *sender) closeResponse(resp *http.Response, size int) {
+	var body io.Reader = io.LimitReader(resp.Body, maxDrainBytes)
+	if _, gunzipped := resp.Body.(*gunzipBody); s.readResp || gunzipped {
+		body = resp.Body
+	}
+
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		s.logger.Warn(fmt.Sprintf("could not read response body of request of %v bytes: %v", size, err), "size", size, "error", err)
+	}
+
+	resp.Body.Close()
+}
+
+// cancelledError reports how far a run got before ctx was cancelled
+func cancelledError(ctx context.Context, completed, total int) error {
+	return fmt.Errorf("cancelled after completing %v of %v sizes: %w", completed, total, context.Cause(ctx))
+}
+
+// stepSizes returns the payload sizes to send based on the start and end steps of cfg
+func stepSizes(cfg SendConfig) ([]int, error) {
+	var start uint = 1
+	var end uint = 25
+	if cfg.StartStep > 0 {
+		if cfg.StartStep >= 32 {
+			return nil, fmt.Errorf("start-step cannot be greater than 31")
+		}
+		start = uint(cfg.StartStep)
+	}
+
+	if cfg.EndStep > 0 {
+		if cfg.EndStep >= 32 {
+			return nil, fmt.Errorf("end-step cannot be greater than 31")
+		}
+		end = uint(cfg.EndStep)
+	}
+
+	if end < start {
+		return nil, fmt.Errorf("end-step cannot be less than start-step")
+	}
+
+	return payloadSizes(cfg, start, end)
+}
+
+// payloadSizes returns the payload sizes to send, from 2^start to 2^end bytes, according to the step mode of cfg
+func payloadSizes(cfg SendConfig, start, end uint) ([]int, error) {
+	minBytes := 1 << start
+	maxBytes := 1 << end
+	sizes := make([]int, 0)
+	switch cfg.StepMode {
+	case StepModePow2, "":
+		for size := minBytes; size <= maxBytes; size <<= 1 {
+			sizes = append(sizes, size)
+		}
+	case StepModeLinear:
+		if cfg.StepSize <= 0 {
+			return nil, errors.New("step-size must be greater than 0")
+		}
+
+		for size := minBytes; size <= maxBytes; size += cfg.StepSize {
+			sizes = append(sizes, size)
+		}
+	default:
+		return nil, fmt.Errorf("invalid step-mode %v, must be one of: %v, %v", cfg.StepMode, StepModePow2, StepModeLinear)
+	}
+
+	return sizes, nil
+}
+
+func validateMethod(method string) error {
+	for _, m := range validMethods {
+		if method == m {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid method %v, must be one of: %v", method, strings.Join(validMethods, ", "))
+}
@@ -0,0 +1,119 @@
+package reqtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// discardLogger drops every log, keeping test output to failures
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newConnCountingServer starts a server responding to each request with a body of respSize bytes, counting the
+// connections it accepts
+func newConnCountingServer(t *testing.T, respSize int) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, strings.Repeat("x", respSize))
+	}))
+
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func TestSendReusesConnections(t *testing.T) {
+	tests := []struct {
+		name         string
+		respSize     int
+		readResponse bool
+	}{
+		{name: "empty response", respSize: 0},
+		{name: "response within the drain limit", respSize: 16 << 10},
+		{name: "large response read in full", respSize: 256 << 10, readResponse: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, conns := newConnCountingServer(t, tt.respSize)
+			results, err := Send(context.Background(), SendConfig{
+				Address:      server.URL,
+				StartStep:    1,
+				EndStep:      4,
+				Repeat:       5,
+				ReadResponse: tt.readResponse,
+				Logger:       discardLogger(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) != 20 {
+				t.Fatalf("got %v results, want 20", len(results))
+			}
+
+			if n := conns.Load(); n != 1 {
+				t.Errorf("server accepted %v connections for %v sequential requests, want 1", n, len(results))
+			}
+		})
+	}
+}
+
+func TestSendNoKeepAliveOpensConnections(t *testing.T) {
+	server, conns := newConnCountingServer(t, 0)
+	results, err := Send(context.Background(), SendConfig{
+		Address:     server.URL,
+		StartStep:   1,
+		EndStep:     2,
+		Repeat:      3,
+		NoKeepAlive: true,
+		Logger:      discardLogger(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := conns.Load(); n != int64(len(results)) {
+		t.Errorf("server accepted %v connections for %v requests without keep-alives, want one per request", n, len(results))
+	}
+}
+
+func TestSendHostHeader(t *testing.T) {
+	hosts := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		hosts <- r.Host
+	}))
+	defer server.Close()
+
+	_, err := Send(context.Background(), SendConfig{
+		Address:   server.URL,
+		StartStep: 1,
+		EndStep:   1,
+		Header:    http.Header{"Host": {"routed.example"}},
+		Logger:    discardLogger(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if host := <-hosts; host != "routed.example" {
+		t.Errorf("listener got host %q, want routed.example", host)
+	}
+}
@@ -0,0 +1,44 @@
+package reqtest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// ParseSizes parses a list of payload sizes, one per line, each a byte count with an optional suffix such as 512, 64KB,
+// or 1MiB. Blank lines and lines starting with # are skipped
+func ParseSizes(r io.Reader) ([]int, error) {
+	var sizes []int
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		size, err := ParseByteSize(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size on line %v: %w", line, err)
+		}
+
+		if size > math.MaxInt {
+			return nil, fmt.Errorf("size %v on line %v is too large", size, line)
+		}
+
+		sizes = append(sizes, int(size))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read sizes: %w", err)
+	}
+
+	if len(sizes) == 0 {
+		return nil, errors.New("sizes must contain at least one size")
+	}
+
+	return sizes, nil
+}
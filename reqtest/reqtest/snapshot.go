@@ -0,0 +1,23 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// logSnapshots logs the listener's counters each time sig receives, until ctx is done. A nil sig never receives
+func (l *listener) logSnapshots(ctx context.Context, sig <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			requests, bytes, inFlight := l.requests.Load(), l.bytes.Load(), l.inFlight.Load()
+			uptime := time.Since(l.started).Round(time.Second)
+			l.logger.Log(ctx, LevelSummary, fmt.Sprintf("stats: %v requests, %v bytes, %v in flight, up %s", requests, bytes, inFlight, uptime),
+				"requests", requests, "size", bytes, "in_flight", inFlight, "uptime", uptime)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+)
+
+// sendStats accumulates the latencies and byte counts of requests made in send mode
+type sendStats struct {
+	latencies  []time.Duration
+	totalBytes int
+}
+
+func (s *sendStats) record(size int, latency time.Duration) {
+	s.latencies = append(s.latencies, latency)
+	s.totalBytes += size
+}
+
+func (s *sendStats) min() time.Duration {
+	var m time.Duration
+	for i, l := range s.latencies {
+		if i == 0 || l < m {
+			m = l
+		}
+	}
+
+	return m
+}
+
+func (s *sendStats) max() time.Duration {
+	var m time.Duration
+	for _, l := range s.latencies {
+		if l > m {
+			m = l
+		}
+	}
+
+	return m
+}
+
+func (s *sendStats) mean() time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, l := range s.latencies {
+		total += l
+	}
+
+	return total / time.Duration(len(s.latencies))
+}
+
+// percentile returns the latency at the given percentile, expressed between 0 and 1, using the nearest rank method
+func (s *sendStats) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(s.latencies)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func (s *sendStats) logPercentiles(logger *slog.Logger, size int) {
+	p50, p90, p99, mean := s.percentile(0.5), s.percentile(0.9), s.percentile(0.99), s.mean()
+	logger.Log(context.Background(), LevelSummary, fmt.Sprintf("%v bytes over %v requests: p50: %s, p90: %s, p99: %s, mean: %s", size, len(s.latencies), p50, p90, p99, mean), "size", size, "requests", len(s.latencies), "p50", p50, "p90", p90, "p99", p99, "mean", mean)
+}
+
+func (s *sendStats) logSummary(logger *slog.Logger) {
+	logger.Log(context.Background(), LevelSummary, fmt.Sprintf("sent %v requests totaling %v bytes", len(s.latencies), s.totalBytes), "requests", len(s.latencies), "bytes", s.totalBytes)
+	logger.Log(context.Background(), LevelSummary, fmt.Sprintf("latency min: %s, max: %s, mean: %s", s.min(), s.max(), s.mean()), "min", s.min(), "max", s.max(), "mean", s.mean())
+}
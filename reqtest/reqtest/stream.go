@@ -0,0 +1,92 @@
+package reqtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// streamGenerator produces a reader of exactly size bytes of payload, generated as the request reads it rather than up front
+type streamGenerator func(size int) io.Reader
+
+// streamPatterns builds the stream generator for each payload pattern, producing the same kinds of bytes as payloadPatterns
+var streamPatterns = map[string]func(src io.Reader, raw bool) streamGenerator{
+	PatternRandom: func(src io.Reader, raw bool) streamGenerator {
+		if raw {
+			return func(size int) io.Reader { return io.LimitReader(src, int64(size)) }
+		}
+
+		return func(size int) io.Reader { return io.LimitReader(&hexReader{src: src}, int64(size)) }
+	},
+	PatternZeros: func(io.Reader, bool) streamGenerator {
+		return func(size int) io.Reader { return io.LimitReader(zeroReader{}, int64(size)) }
+	},
+	PatternRepeating: func(io.Reader, bool) streamGenerator {
+		return func(size int) io.Reader { return io.LimitReader(&repeatingReader{}, int64(size)) }
+	},
+}
+
+// newStreamGenerator returns the stream generator for the named pattern
+func newStreamGenerator(pattern string, src io.Reader, raw bool) (streamGenerator, error) {
+	newGenerator, ok := streamPatterns[pattern]
+	if !ok {
+		return nil, fmt.Errorf("invalid pattern %v", pattern)
+	}
+
+	return newGenerator(src, raw), nil
+}
+
+// attemptStream makes a single request with a streamed payload of size bytes, so memory stays flat however large it is
+func (s *sender) attemptStream(ctx context.Context, size int) (Result, error) {
+	if !s.budget.reserve(int64(size)) {
+		return Result{Size: size}, errBudgetExhausted
+	}
+
+	return s.do(ctx, s.method, s.address, nil, Result{Size: size}, s.stream(size), int64(size), [sha256.Size]byte{})
+}
+
+// hexReader hex encodes bytes read from src as it is read, holding back the second character of a byte that doesn't fit
+type hexReader struct {
+	src     io.Reader
+	raw     []byte
+	encoded []byte
+	pending []byte
+}
+
+func (h *hexReader) Read(p []byte) (int, error) {
+	if len(h.pending) > 0 {
+		n := copy(p, h.pending)
+		h.pending = h.pending[n:]
+		return n, nil
+	}
+
+	half := max(len(p)/2, 1)
+	if cap(h.raw) < half {
+		h.raw = make([]byte, half)
+		h.encoded = make([]byte, hex.EncodedLen(half))
+	}
+
+	if _, err := io.ReadFull(h.src, h.raw[:half]); err != nil {
+		return 0, fmt.Errorf("failed to generate bytes: %w", err)
+	}
+
+	encoded := h.encoded[:hex.Encode(h.encoded, h.raw[:half])]
+	n := copy(p, encoded)
+	h.pending = encoded[n:]
+	return n, nil
+}
+
+// zeroReader produces an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+var (
+	_ io.Reader = (*hexReader)(nil)
+	_ io.Reader = zeroReader{}
+)
@@ -0,0 +1,68 @@
+package reqtest
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func TestStreamAllocationBounded(t *testing.T) {
+	const (
+		size = 256 << 20
+		// generous headroom over the copy buffers, still orders of magnitude below size
+		maxAlloc = 4 << 20
+	)
+
+	tests := []struct {
+		pattern string
+		raw     bool
+	}{
+		{pattern: PatternRandom},
+		{pattern: PatternRandom, raw: true},
+		{pattern: PatternZeros},
+		{pattern: PatternRepeating},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v/raw=%v", tt.pattern, tt.raw), func(t *testing.T) {
+			stream, err := newStreamGenerator(tt.pattern, newSeededReader(1), tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			n, err := io.Copy(io.Discard, stream(size))
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != size {
+				t.Fatalf("streamed %v bytes, want %v", n, size)
+			}
+
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > maxAlloc {
+				t.Errorf("streaming %v bytes allocated %v bytes, want at most %v", size, allocated, maxAlloc)
+			}
+		})
+	}
+}
+
+func BenchmarkStreamHex(b *testing.B) {
+	const size = 64 << 20
+	stream, err := newStreamGenerator(PatternRandom, newSeededReader(1), false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := io.Copy(io.Discard, stream(size)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// SendTargets runs Send against each of targets in place of cfg.Address, one after another or all at once if parallel is
+// set. Each result is labelled with the target it was sent to, and results are returned grouped in the order of targets.
+// Limits such as cfg.MaxTotalBytes apply to each target separately. Once every target is done a summary comparing them is logged.
+func SendTargets(ctx context.Context, cfg SendConfig, targets []string, parallel bool) (Results, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = defaultLogger()
+	}
+
+	logger := cfg.Logger
+	perTarget := make([]Results, len(targets))
+	errs := make([]error, len(targets))
+	run := func(i int) {
+		target := targets[i]
+		targetCfg := cfg
+		targetCfg.Address = target
+		targetCfg.Logger = logger.With("target", target)
+		logger.Info(fmt.Sprintf("sending to target %v", target), "target", target)
+		results, err := Send(ctx, targetCfg)
+		for j := range results {
+			results[j].Target = target
+		}
+
+		perTarget[i] = results
+		if err != nil {
+			errs[i] = fmt.Errorf("target %v: %w", target, err)
+		}
+	}
+
+	if parallel {
+		var wg sync.WaitGroup
+		for i := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(i)
+			}()
+		}
+
+		wg.Wait()
+	} else {
+		for i := range targets {
+			if ctx.Err() != nil {
+				break
+			}
+
+			run(i)
+		}
+	}
+
+	var results Results
+	for _, r := range perTarget {
+		results = append(results, r...)
+	}
+
+	if !cfg.DryRun {
+		logTargetSummary(logger, targets, perTarget)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// logTargetSummary logs a line per target with its request count, failures and latency percentiles, padded so the
+// targets line up side by side
+func logTargetSummary(logger *slog.Logger, targets []string, perTarget []Results) {
+	width := 0
+	for _, target := range targets {
+		width = max(width, len(target))
+	}
+
+	for i, target := range targets {
+		var stats sendStats
+		failed := 0
+		for _, result := range perTarget[i] {
+			if result.Error != "" {
+				failed++
+				continue
+			}
+
+			stats.record(result.Size, result.Duration)
+		}
+
+		p50, p90, p99, mean := stats.percentile(0.5), stats.percentile(0.9), stats.percentile(0.99), stats.mean()
+		logger.Log(context.Background(), LevelSummary, fmt.Sprintf("%-*s  %v requests, %v failed, %v bytes, p50: %s, p90: %s, p99: %s, mean: %s", width, target, len(perTarget[i]), failed, stats.totalBytes, p50, p90, p99, mean), "target", target, "requests", len(perTarget[i]), "failed", failed, "bytes", stats.totalBytes, "p50", p50, "p90", p90, "p99", p99, "mean", mean)
+	}
+}
@@ -0,0 +1,60 @@
+package reqtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// templateData is what a body template is rendered with for each request
+type templateData struct {
+	// Index counts the requests rendered from the template, starting at 0
+	Index int64
+	// UUID is a random version 4 UUID
+	UUID string
+	// Timestamp is when the body was rendered in RFC 3339 format
+	Timestamp string
+}
+
+// templatePayload parses text as a text/template and returns a generator rendering it with a new templateData on every call,
+// along with the size of a body rendered for the first request to stand in for the varying sizes in logs and plans
+func templatePayload(text string) (payloadGenerator, int, error) {
+	tmpl, err := template.New("body").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not parse template: %w", err)
+	}
+
+	render := func(index int64) ([]byte, error) {
+		data := templateData{Index: index, UUID: newUUID(), Timestamp: time.Now().Format(time.RFC3339Nano)}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("could not render template: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	preview, err := render(0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var index atomic.Int64
+	generator := func(int) ([]byte, error) {
+		return render(index.Add(1) - 1)
+	}
+
+	return generator, len(preview), nil
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
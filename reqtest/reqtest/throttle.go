@@ -0,0 +1,75 @@
+package reqtest
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteSizeSuffixes maps the accepted size suffixes to their multipliers, longest suffixes first so they match before their prefixes
+var byteSizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// ParseByteSize parses a byte count with an optional suffix such as 512, 64KB, 1MB or 1MiB
+func ParseByteSize(str string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(str))
+	multiplier := int64(1)
+	for _, s := range byteSizeSuffixes {
+		if strings.HasSuffix(trimmed, s.suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, s.suffix))
+			multiplier = s.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", str)
+	}
+
+	return n * multiplier, nil
+}
+
+// throttledReader paces reads from the underlying reader so they don't exceed rate bytes per second
+type throttledReader struct {
+	r     io.Reader
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func newThrottledReader(r io.Reader, rate int64) *throttledReader {
+	return &throttledReader{r: r, rate: rate}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+
+	// read at most a tenth of a second's worth at a time so the pacing stays smooth
+	if chunk := max(t.rate/10, 1); int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := t.r.Read(p)
+	t.read += int64(n)
+	expected := time.Duration(float64(t.read) / float64(t.rate) * float64(time.Second))
+	if wait := expected - time.Since(t.start); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	return n, err
+}
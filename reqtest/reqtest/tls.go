@@ -0,0 +1,256 @@
+package reqtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+)
+
+// generateSelfSignedCert creates an in memory ECDSA certificate valid for localhost and 127.0.0.1.
+// The returned fingerprint is the SHA-256 of the DER encoded certificate.
+func generateSelfSignedCert() (tls.Certificate, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             now.Add(-1 * time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+
+	return cert, fingerprint(der), nil
+}
+
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// clientTLSConfig creates the TLS config of the sender, presenting the certificate and key at certFile and keyFile if set,
+// and trusting the CA certificates in caFile instead of the system roots if set
+func clientTLSConfig(certFile, keyFile, caFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// loadCertPool reads the PEM encoded certificates in path into a pool
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ca certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in %v", path)
+	}
+
+	return pool, nil
+}
+
+// withTLSClientConfig sets the TLS config of transport, which is a clone of the default transport when nil
+func withTLSClientConfig(transport http.RoundTripper, config *tls.Config) http.RoundTripper {
+	switch t := transport.(type) {
+	case *http.Transport:
+		t.TLSClientConfig = config
+	case *http2.Transport:
+		t.TLSClientConfig = config
+	case *http3.Transport:
+		t.TLSClientConfig = config
+	default:
+		clone := http.DefaultTransport.(*http.Transport).Clone()
+		clone.TLSClientConfig = config
+		return clone
+	}
+
+	return transport
+}
+
+// requireClientCerts makes server require clients to present a certificate signed by a CA in caFile, failing the handshake
+// of any that don't, and logs the subject of each verified client certificate
+func (l *listener) requireClientCerts(server *http.Server, caFile string) error {
+	pool, err := loadCertPool(caFile)
+	if err != nil {
+		return err
+	}
+
+	if server.TLSConfig == nil {
+		// the tls-cert and tls-key files are added to the config when serving
+		server.TLSConfig = &tls.Config{}
+	}
+
+	server.TLSConfig.ClientCAs = pool
+	server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	server.TLSConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) > 0 {
+			subject := cs.PeerCertificates[0].Subject.String()
+			l.logger.Info(fmt.Sprintf("verified client certificate %v", subject), "subject", subject)
+		}
+
+		return nil
+	}
+
+	l.logger.Info(fmt.Sprintf("requiring client certificates signed by a CA in %v", caFile), "client_ca", caFile)
+	return nil
+}
+
+// tlsVersions maps the names accepted by ParseTLSVersion to their versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as 1.2
+func ParseTLSVersion(str string) (uint16, error) {
+	version, ok := tlsVersions[strings.TrimPrefix(strings.TrimSpace(str), "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %q, must be one of 1.0, 1.1, 1.2, or 1.3", str)
+	}
+
+	return version, nil
+}
+
+// ParseCipherSuites parses a comma separated list of cipher suite names, such as
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, including suites considered insecure
+func ParseCipherSuites(str string) ([]uint16, error) {
+	ids := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ids[suite.Name] = suite.ID
+	}
+
+	var suites []uint16
+	for _, name := range strings.Split(str, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}
+
+// applyTLSPolicy restricts the TLS versions and cipher suites server negotiates, failing the handshake of clients that don't
+// support them, and logs the version and cipher suite negotiated for each connection
+func (l *listener) applyTLSPolicy(server *http.Server, minVersion, maxVersion uint16, ciphers []uint16) {
+	if server.TLSConfig == nil {
+		// the tls-cert and tls-key files are added to the config when serving
+		server.TLSConfig = &tls.Config{}
+	}
+
+	server.TLSConfig.MinVersion = minVersion
+	server.TLSConfig.MaxVersion = maxVersion
+	server.TLSConfig.CipherSuites = ciphers
+	verify := server.TLSConfig.VerifyConnection
+	server.TLSConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		version, cipher := tls.VersionName(cs.Version), tls.CipherSuiteName(cs.CipherSuite)
+		l.logger.Info(fmt.Sprintf("negotiated %v with %v", version, cipher), "tls_version", version, "cipher_suite", cipher)
+		if verify != nil {
+			return verify(cs)
+		}
+
+		return nil
+	}
+
+	names := make([]string, len(ciphers))
+	for i, id := range ciphers {
+		names[i] = tls.CipherSuiteName(id)
+	}
+
+	if minVersion != 0 || maxVersion != 0 {
+		l.logger.Info(fmt.Sprintf("restricting TLS to versions %v to %v", versionName(minVersion, "the default minimum"), versionName(maxVersion, "the default maximum")), "tls_min_version", versionName(minVersion, ""), "tls_max_version", versionName(maxVersion, ""))
+	}
+
+	if len(ciphers) > 0 {
+		l.logger.Info(fmt.Sprintf("restricting TLS 1.2 and earlier to cipher suites %v", strings.Join(names, ", ")), "cipher_suites", names)
+		if maxVersion == 0 || maxVersion >= tls.VersionTLS13 {
+			l.logger.Warn("warning: tls-ciphers only restrict TLS 1.2 and earlier, TLS 1.3 cipher suites are not configurable, use tls-max-version 1.2 to restrict every handshake", "tls_max_version", versionName(maxVersion, ""))
+		}
+
+		// net/http refuses to serve HTTP/2 with cipher suites that could negotiate TLS 1.2 without one HTTP/2 requires
+		if minVersion < tls.VersionTLS13 && !slices.ContainsFunc(ciphers, isHTTP2Cipher) {
+			server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+			l.logger.Warn("warning: tls-ciphers include neither TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 nor TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, which HTTP/2 requires, serving HTTP/1.1 only", "cipher_suites", names, "http2", false)
+		}
+	}
+}
+
+// isHTTP2Cipher reports whether cipher is one of the suites HTTP/2 requires the server to support when negotiating TLS 1.2
+func isHTTP2Cipher(cipher uint16) bool {
+	return cipher == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || cipher == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+}
+
+// versionName returns the name of a TLS version, or unset if it is 0
+func versionName(version uint16, unset string) string {
+	if version == 0 {
+		return unset
+	}
+
+	return tls.VersionName(version)
+}
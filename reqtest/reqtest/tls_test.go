@@ -0,0 +1,44 @@
+package reqtest
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListenTLSCiphersHTTP2(t *testing.T) {
+	tests := []struct {
+		name   string
+		cipher uint16
+		proto  string
+	}{
+		{name: "cipher HTTP/2 requires", cipher: tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, proto: "HTTP/2.0"},
+		{name: "cipher without HTTP/2", cipher: tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, proto: "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := startListener(t, ListenConfig{TLSSelfSigned: true, TLSCiphers: []uint16{tt.cipher}})
+			client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+				// TLS 1.3 suites aren't configurable, so the handshake is limited to TLS 1.2 for the cipher to apply
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12},
+				ForceAttemptHTTP2: true,
+			}}
+
+			resp, err := client.Get("https://" + address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+			if resp.Proto != tt.proto {
+				t.Errorf("got %v, want %v", resp.Proto, tt.proto)
+			}
+
+			if resp.TLS.CipherSuite != tt.cipher {
+				t.Errorf("negotiated %v, want %v", tls.CipherSuiteName(resp.TLS.CipherSuite), tls.CipherSuiteName(tt.cipher))
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package reqtest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http/httptrace"
+	"time"
+)
+
+// requestTrace records the connection used by a request and when each phase of the request happened
+type requestTrace struct {
+	conn         httptrace.GotConnInfo
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	continued    bool
+	// conns records the request's use of the connection pool when set
+	conns *connStats
+}
+
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart: func(string, string) {
+			// dialing may try several addresses, the first attempt starts the connect phase
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotConn:              t.gotConn,
+		PutIdleConn:          t.putIdleConn,
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+		Got100Continue:       func() { t.continued = true },
+	}
+}
+
+func (t *requestTrace) gotConn(info httptrace.GotConnInfo) {
+	t.conn = info
+	if t.conns != nil {
+		t.conns.gotConn(info)
+	}
+}
+
+func (t *requestTrace) putIdleConn(err error) {
+	if t.conns != nil {
+		t.conns.putIdleConn(t.conn.Conn, err)
+	}
+}
+
+// logBreakdown logs how long each phase of a request that started at start and took total took. Phases that didn't happen,
+// such as dialing on a reused connection, are logged as 0
+func (t *requestTrace) logBreakdown(logger *slog.Logger, size int, start time.Time, total time.Duration) {
+	dns := phase(t.dnsStart, t.dnsDone)
+	connect := phase(t.connectStart, t.connectDone)
+	tlsHandshake := phase(t.tlsStart, t.tlsDone)
+	ttfb := phase(start, t.firstByte)
+	logger.Info(fmt.Sprintf("request of %v bytes trace: dns %s, connect %s, tls %s, ttfb %s, total %s", size, dns, connect, tlsHandshake, ttfb, total),
+		"size", size, "dns", dns, "connect", connect, "tls", tlsHandshake, "ttfb", ttfb, "total", total)
+}
+
+// phase returns the time between start and end, or 0 if the phase didn't complete
+func phase(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+
+	return end.Sub(start)
+}
@@ -0,0 +1,80 @@
+package reqtest
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"slices"
+)
+
+// withTrailer sends trailer after the bodies of the responses written by next. The trailer keys are declared in the Trailer
+// header when the final status is written, and any Content-Length next set is dropped so HTTP/1.1 responses are chunked,
+// as trailers can only follow a chunked body
+func withTrailer(trailer http.Header, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tw := &trailerWriter{ResponseWriter: w, trailer: trailer}
+		next(tw, r)
+		if tw.hijacked {
+			// the connection was taken over by next, such as to drop it, so there is no response to declare a trailer on
+			return
+		}
+
+		if !tw.declared {
+			// the trailer must be declared before the status is written, which http.Server would otherwise do on return
+			tw.WriteHeader(http.StatusOK)
+		}
+
+		for key, values := range trailer {
+			w.Header()[key] = slices.Clone(values)
+		}
+	}
+}
+
+// trailerWriter is a http.ResponseWriter that declares the keys of trailer before the status is written
+type trailerWriter struct {
+	http.ResponseWriter
+	trailer  http.Header
+	declared bool
+	hijacked bool
+}
+
+func (t *trailerWriter) declare() {
+	if t.declared {
+		return
+	}
+
+	t.declared = true
+	header := t.ResponseWriter.Header()
+	header.Del("Content-Length")
+	for key := range t.trailer {
+		header.Add("Trailer", key)
+	}
+}
+
+func (t *trailerWriter) WriteHeader(code int) {
+	if code >= 200 {
+		t.declare()
+	}
+
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *trailerWriter) Write(b []byte) (int, error) {
+	t.declare()
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which echo needs to enable full duplex
+func (t *trailerWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Hijack passes through to the underlying writer, recording that the response is no longer written through it
+func (t *trailerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(t.ResponseWriter).Hijack()
+	if err == nil {
+		t.hijacked = true
+	}
+
+	return conn, buf, err
+}
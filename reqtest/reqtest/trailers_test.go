@@ -0,0 +1,71 @@
+package reqtest
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithTrailer(t *testing.T) {
+	trailer := http.Header{"X-Checksum": {"abc"}}
+	server := httptest.NewServer(withTrailer(trailer, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "hello" {
+		t.Errorf("got body %q, want hello", body)
+	}
+
+	if got := resp.Trailer.Get("X-Checksum"); got != "abc" {
+		t.Errorf("got trailer %q, want abc", got)
+	}
+}
+
+func TestWithTrailerHijacked(t *testing.T) {
+	var logs strings.Builder
+	server := httptest.NewUnstartedServer(withTrailer(http.Header{"X-Checksum": {"abc"}}, func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 7\r\n\r\npartial")
+		buf.Flush()
+	}))
+	server.Config.ErrorLog = log.New(&logs, "", 0)
+	server.Start()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "partial" {
+		t.Fatalf("got %q, %v, want the hijacked response", body, err)
+	}
+
+	server.Close()
+	if logs.Len() > 0 {
+		t.Errorf("server logged errors after the connection was hijacked: %v", logs.String())
+	}
+}
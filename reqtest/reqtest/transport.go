@@ -0,0 +1,31 @@
+package reqtest
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// tuneTransport applies the keep-alive and connection limit settings to transport, which is a clone of the default transport
+// when nil, and logs the effective settings
+func tuneTransport(logger *slog.Logger, transport http.RoundTripper, noKeepAlive bool, maxConnsPerHost int) *http.Transport {
+	t, ok := transport.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	t.DisableKeepAlives = noKeepAlive
+	t.MaxConnsPerHost = maxConnsPerHost
+	keepAlive, maxConns := "enabled", "unlimited"
+	if noKeepAlive {
+		keepAlive = "disabled"
+	}
+
+	if maxConnsPerHost > 0 {
+		maxConns = strconv.Itoa(maxConnsPerHost)
+	}
+
+	logger.Info(fmt.Sprintf("using transport with keep-alives %v and %v max connections per host", keepAlive, maxConns), "keep_alive", !noKeepAlive, "max_conns_per_host", maxConnsPerHost)
+	return t
+}
@@ -0,0 +1,27 @@
+package reqtest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// unixPrefix marks an address as the path of a unix domain socket, such as unix:/tmp/reqtest.sock
+const unixPrefix = "unix:"
+
+// unixSocketPath returns the socket path of address and whether address is a unix domain socket address
+func unixSocketPath(address string) (string, bool) {
+	return strings.CutPrefix(address, unixPrefix)
+}
+
+// unixTransport creates a transport that dials the unix domain socket at path for every request, regardless of the request's host
+func unixTransport(path string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+
+	return transport
+}
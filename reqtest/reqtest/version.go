@@ -0,0 +1,69 @@
+package reqtest
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version, Commit and BuildDate describe the build, and are set at build time with -ldflags such as
+// "-X requestechoer/reqtest.Version=v1.2.0 -X requestechoer/reqtest.Commit=abc123 -X requestechoer/reqtest.BuildDate=2024-01-02T15:04:05Z".
+// Any left unset are read from the build info embedded by the go command, see ReadBuildInfo
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// BuildInfo describes the build of reqtest that is running
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+}
+
+// ReadBuildInfo returns the build info set with -ldflags, falling back to the module version and version control info
+// embedded by the go command, and then to dev and unknown
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		var modified bool
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			case setting.Key == "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+
+		if modified && Commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	return info
+}
+
+// DefaultUserAgent is the User-Agent requests are sent with unless SendConfig.UserAgent is set
+func DefaultUserAgent() string {
+	return "reqtest/" + ReadBuildInfo().Version
+}
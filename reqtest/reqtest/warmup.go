@@ -0,0 +1,54 @@
+package reqtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// warmUp sends n throwaway requests of size before the measured run, spread across concurrency workers so the pool holds a
+// connection for each worker. Each is a single attempt whose result isn't recorded, and failures are only logged
+func (s *sender) warmUp(ctx context.Context, n, size, concurrency int) {
+	s.logger.Info(fmt.Sprintf("warming up with %v requests of %v bytes...", n, size), "warmup", n, "size", size)
+	start := time.Now()
+	var (
+		wg     sync.WaitGroup
+		next   atomic.Int64
+		failed atomic.Int64
+	)
+
+	for range min(concurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && next.Add(1) <= int64(n) {
+				var err error
+				if s.stream != nil {
+					_, err = s.attemptStream(ctx, size)
+				} else {
+					var (
+						body   []byte
+						header http.Header
+					)
+
+					body, header, err = s.body(size)
+					if err == nil {
+						_, err = s.attempt(ctx, s.method, s.address, header, body)
+					}
+				}
+
+				if err != nil && ctx.Err() == nil {
+					s.logger.Warn(fmt.Sprintf("warm-up request of %v bytes failed: %v", size, err), "size", size, "error", err)
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	s.logger.Info(fmt.Sprintf("warmed up in %s, %v of %v requests failed", elapsed, failed.Load(), n), "warmup", n, "failed", failed.Load(), "duration", elapsed)
+}
@@ -0,0 +1,166 @@
+package reqtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// websocketPath is where WebSocket connections are accepted when ListenConfig.WebSocket is set
+const websocketPath = "/ws"
+
+// wsFrame is a single WebSocket message along with whether it was sent as text or binary
+type wsFrame struct {
+	data        []byte
+	payloadType byte
+}
+
+// frameCodec receives and sends whole messages, keeping each message's payload type so text is echoed as text
+var frameCodec = websocket.Codec{
+	Marshal: func(v any) ([]byte, byte, error) {
+		f := v.(*wsFrame)
+		return f.data, f.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v any) error {
+		f := v.(*wsFrame)
+		f.data, f.payloadType = data, payloadType
+		return nil
+	},
+}
+
+// websocketHandler upgrades requests to WebSocket connections and echoes every message received back to the client.
+// Connections are handled outside the wrappers of the HTTP handler, which can't be hijacked, and count towards max-requests
+func (l *listener) websocketHandler() http.HandlerFunc {
+	server := websocket.Server{
+		// clients other than browsers don't send an Origin, so any origin is accepted
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   l.echoWebSocket,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok || r.ProtoMajor != 1 {
+			l.logger.Warn(fmt.Sprintf("rejecting %v WebSocket request, only HTTP/1.1 connections can be upgraded", r.Proto), "proto", r.Proto, "status", http.StatusHTTPVersionNotSupported)
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			l.logger.Warn(fmt.Sprintf("rejecting %v request to %v that is not a WebSocket upgrade", r.Method, websocketPath), "method", r.Method, "path", r.URL.Path, "status", http.StatusBadRequest)
+			http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+			return
+		}
+
+		done, ok := l.admit(w)
+		if !ok {
+			return
+		}
+
+		defer done()
+		server.ServeHTTP(w, r)
+	}
+}
+
+// echoWebSocket echoes messages on ws until the client closes it, counting each message's bytes like a request body
+func (l *listener) echoWebSocket(ws *websocket.Conn) {
+	l.requests.Add(1)
+	l.inFlight.Add(1)
+	defer l.inFlight.Add(-1)
+	if !l.websockets.add(ws) {
+		ws.Close()
+		return
+	}
+
+	defer l.websockets.remove(ws)
+	remote := ws.Request().RemoteAddr
+	l.logger.Info(fmt.Sprintf("accepted WebSocket connection from %v", remote), "remote_addr", remote)
+	start := time.Now()
+	var messages, total int64
+	for {
+		var frame wsFrame
+		if err := frameCodec.Receive(ws, &frame); err != nil {
+			if l.websockets.isClosing() {
+				l.logger.Info(fmt.Sprintf("closed WebSocket connection from %v at shutdown after %v messages", remote, messages), "remote_addr", remote, "messages", messages, "size", total)
+				return
+			}
+
+			if !errors.Is(err, io.EOF) {
+				l.logger.Error(fmt.Sprintf("WebSocket connection from %v failed after %v messages: %v", remote, messages, err), "remote_addr", remote, "messages", messages, "size", total, "error", err)
+				return
+			}
+
+			break
+		}
+
+		n := int64(len(frame.data))
+		messages++
+		total += n
+		l.bytes.Add(n)
+		l.logger.Info(fmt.Sprintf("received WebSocket message of %v bytes", n), "remote_addr", remote, "size", n, "binary", frame.payloadType == websocket.BinaryFrame)
+		if err := frameCodec.Send(ws, &frame); err != nil {
+			l.logger.Error(fmt.Sprintf("error echoing WebSocket message: %v", err), "remote_addr", remote, "error", err)
+			return
+		}
+	}
+
+	duration := time.Since(start)
+	l.logger.Info(fmt.Sprintf("WebSocket connection from %v closed after %v messages totaling %v bytes in %s", remote, messages, total, duration), "remote_addr", remote, "messages", messages, "size", total, "duration", duration)
+}
+
+// websocketConns tracks open WebSocket connections, which http.Server.Shutdown doesn't wait for or close as they are hijacked
+type websocketConns struct {
+	mu      sync.Mutex
+	conns   map[*websocket.Conn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// add tracks ws, returning false if connections are already being closed so ws should be closed straight away
+func (c *websocketConns) add(ws *websocket.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return false
+	}
+
+	if c.conns == nil {
+		c.conns = make(map[*websocket.Conn]struct{})
+	}
+
+	c.conns[ws] = struct{}{}
+	c.wg.Add(1)
+	return true
+}
+
+// remove stops tracking ws once its handler has returned
+func (c *websocketConns) remove(ws *websocket.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.conns, ws)
+	c.wg.Done()
+}
+
+func (c *websocketConns) isClosing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closing
+}
+
+// closeAll closes every open connection and waits for their handlers to return, returning how many were closed
+func (c *websocketConns) closeAll() int {
+	c.mu.Lock()
+	c.closing = true
+	closed := len(c.conns)
+	for ws := range c.conns {
+		ws.Close()
+	}
+
+	c.mu.Unlock()
+	c.wg.Wait()
+	return closed
+}
@@ -0,0 +1,74 @@
+package reqtest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// freeAddress returns a localhost address with a port that was free when checked
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestListenClosesWebSocketsOnShutdown(t *testing.T) {
+	address := freeAddress(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Listen(ctx, ListenConfig{Address: address, WebSocket: true, ShutdownTimeout: time.Second, Logger: discardLogger()})
+	}()
+
+	var (
+		ws  *websocket.Conn
+		err error
+	)
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if ws, err = websocket.Dial("ws://"+address+websocketPath, "", "http://localhost/"); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ws.Close()
+	if err := websocket.Message.Send(ws, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	var echoed string
+	if err := websocket.Message.Receive(ws, &echoed); err != nil || echoed != "hello" {
+		t.Fatalf("got %q, %v, want the message echoed", echoed, err)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return with a WebSocket connection open")
+	}
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var netErr net.Error
+	if err := websocket.Message.Receive(ws, &echoed); err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatalf("WebSocket connection was left open after Listen returned: %v", err)
+	}
+}
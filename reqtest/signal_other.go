@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// statsSignal returns nil as there is no SIGUSR1 to log a snapshot of the listener's counters on, so snapshots are never logged
+func statsSignal() <-chan os.Signal {
+	return nil
+}
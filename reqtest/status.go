@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+
+	"requestechoer/reqtest"
+)
+
+// statusVar defines a repeatable status code flag with the given name and usage
+func statusVar(name, usage string) *statusFlag {
+	s := &statusFlag{}
+	flag.Var(s, name, usage)
+	return s
+}
+
+// statusFlag collects repeated flags, each a status code or comma separated list of them, into a set of status codes
+type statusFlag struct {
+	codes []int
+}
+
+func (s *statusFlag) String() string {
+	if s == nil || s.codes == nil {
+		return ""
+	}
+
+	parts := make([]string, len(s.codes))
+	for i, code := range s.codes {
+		parts[i] = strconv.Itoa(code)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (s *statusFlag) Set(value string) error {
+	codes, err := reqtest.ParseStatuses(value)
+	if err != nil {
+		return err
+	}
+
+	s.codes = append(s.codes, codes...)
+	return nil
+}